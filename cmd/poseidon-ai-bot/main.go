@@ -1,22 +1,109 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/widiskel/poseidon-voice-bot/internal/app"
+	"github.com/widiskel/poseidon-voice-bot/internal/config"
 	"github.com/widiskel/poseidon-voice-bot/internal/utils/logger"
 	"github.com/widiskel/poseidon-voice-bot/internal/utils/spinner"
 )
 
+// tools work on local state only and print to stdout, so they run without the spinner UI.
+var tools = map[string]interface {
+	register(fs *flag.FlagSet)
+	run(a *app.App) error
+}{
+	"report": &reportCmd{},
+	"resume": &resumeCmd{},
+	"outbox": &outboxCmd{},
+}
+
 func main() {
-	_ = logger.Init("logs/app.log")
+	cmd, args := "run", os.Args[1:]
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		cmd, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	configPath := config.RegisterFlags(fs)
+	tool, isTool := tools[cmd]
+	if isTool {
+		tool.register(fs)
+	}
+	dryRun := false
+	if cmd == "run" {
+		fs.BoolVar(&dryRun, "dry-run", false, "log in and plan one cycle, print the plan and exit without uploading")
+	}
+	_ = fs.Parse(args)
+
+	cfg, err := config.Resolve(*configPath, fs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if isTool {
+		if err := tool.run(app.New(cfg)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cmd == "run" {
+		if err := cfg.CheckSource(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	_ = logger.Init(cfg.Paths.Log)
 	defer logger.Close()
+	logger.SetLevel(cfg.Log.Level)
 
 	spinner.StartUISystem()
 	defer spinner.StopUISystem()
 
-	if err := app.New().Run(); err != nil {
-		panic(err)
+	a := app.New(cfg)
+
+	switch cmd {
+	case "run":
+		if !dryRun {
+			err = a.Run(ctx)
+			break
+		}
+		var plan bytes.Buffer
+		if err = a.DryRun(ctx, &plan); err == nil {
+			spinner.StopUISystem()
+			fmt.Print(plan.String())
+			return
+		}
+	case "export":
+		err = a.Export(ctx)
+	case "submit":
+		err = a.Submit(ctx)
+	default:
+		spinner.StopUISystem()
+		fmt.Fprintf(os.Stderr, "unknown command %q (use run, export, submit, report, resume or outbox)\n", cmd)
+		os.Exit(2)
+	}
+	if err != nil && ctx.Err() == nil {
+		// os.Exit skips the deferred calls, so the UI and log file are closed first.
+		spinner.StopUISystem()
+		logger.Close()
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
 	time.Sleep(1 * time.Second)
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/app"
+	"github.com/widiskel/poseidon-voice-bot/internal/outbox"
+)
+
+type outboxCmd struct {
+	requeue string
+}
+
+func (f *outboxCmd) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.requeue, "requeue", "", "move this dead-lettered file ID back to pending")
+}
+
+func (f *outboxCmd) run(a *app.App) error {
+	store := a.Outbox()
+	if f.requeue != "" {
+		ok, err := store.Requeue(f.requeue)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("no dead-lettered upload %s", f.requeue)
+		}
+		fmt.Printf("Requeued %s, it will be retried on the next cycle\n", f.requeue)
+		return nil
+	}
+
+	pending, err := store.Pending("")
+	if err != nil {
+		return err
+	}
+	dead, err := store.Dead()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Pending (%d)\n", len(pending))
+	if err := writeOutboxItems(pending); err != nil {
+		return err
+	}
+	fmt.Printf("\nDead letter (%d)\n", len(dead))
+	return writeOutboxItems(dead)
+}
+
+func writeOutboxItems(items []outbox.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE ID\tACCOUNT\tCAMPAIGN\tSTATE\tATTEMPTS\tUPDATED\tLAST ERROR")
+	for _, it := range items {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			it.FileID, it.Account, it.CampaignName, it.State, it.Attempts, it.UpdatedAt.Local().Format(time.DateTime), it.LastError)
+	}
+	return tw.Flush()
+}
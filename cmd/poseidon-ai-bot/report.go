@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/app"
+	"github.com/widiskel/poseidon-voice-bot/internal/report"
+)
+
+type reportCmd struct {
+	format  string
+	from    string
+	to      string
+	account string
+	out     string
+}
+
+func (f *reportCmd) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.format, "format", report.FormatTable, "report format: table, csv, json or html")
+	fs.StringVar(&f.from, "from", "", "first day to include, YYYY-MM-DD")
+	fs.StringVar(&f.to, "to", "", "last day to include, YYYY-MM-DD")
+	fs.StringVar(&f.account, "account", "", "only report this account email")
+	fs.StringVar(&f.out, "out", "", "write the report to this file instead of stdout")
+}
+
+func (f *reportCmd) run(a *app.App) error {
+	opts := report.Options{Account: f.account}
+	var err error
+	if f.from != "" {
+		if opts.From, err = time.ParseInLocation(time.DateOnly, f.from, time.Local); err != nil {
+			return fmt.Errorf("-from: %w", err)
+		}
+	}
+	if f.to != "" {
+		if opts.To, err = time.ParseInLocation(time.DateOnly, f.to, time.Local); err != nil {
+			return fmt.Errorf("-to: %w", err)
+		}
+		opts.To = opts.To.AddDate(0, 0, 1)
+	}
+
+	if f.out == "" {
+		return a.Report(os.Stdout, f.format, opts)
+	}
+	file, err := os.Create(f.out)
+	if err != nil {
+		return err
+	}
+	if err := a.Report(file, f.format, opts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/app"
+)
+
+type resumeCmd struct {
+	account  string
+	campaign string
+}
+
+func (f *resumeCmd) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.account, "account", "", "account email to resume; without it the paused campaigns are listed")
+	fs.StringVar(&f.campaign, "campaign", "", "only resume this campaign ID")
+}
+
+func (f *resumeCmd) run(a *app.App) error {
+	if f.account == "" {
+		pauses, err := a.Paused()
+		if err != nil {
+			return err
+		}
+		if len(pauses) == 0 {
+			fmt.Println("Nothing is paused.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ACCOUNT\tCAMPAIGN ID\tCAMPAIGN\tREASON\tPAUSED AT")
+		for _, p := range pauses {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", p.Account, p.CampaignID, p.CampaignName, p.Reason(), p.PausedAt.Local().Format(time.DateTime))
+		}
+		return tw.Flush()
+	}
+
+	n, err := a.Resume(f.account, f.campaign)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("nothing paused for %s", f.account)
+	}
+	fmt.Printf("Resumed %d campaign(s) for %s\n", n, f.account)
+	return nil
+}
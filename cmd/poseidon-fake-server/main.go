@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/fakeserver"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8787", "listen address")
+	failValidate := flag.Int("fail-validate", 0, "answer the first N POST /files calls with 503")
+	dropValidate := flag.Int("drop-validate", 0, "validate the first N POST /files calls but drop the response")
+	failPut := flag.Int("fail-put", 0, "answer the first N presigned PUTs with 503")
+	failGet := flag.Int("fail-get", 0, "answer the first N API GETs with 503 and Retry-After: 1")
+	presignTTL := flag.Duration("presign-ttl", 15*time.Minute, "lifetime of presigned upload URLs")
+	flag.Parse()
+
+	srv, err := fakeserver.Listen(*addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer srv.Close()
+	srv.FailValidations(*failValidate)
+	srv.DropValidations(*dropValidate)
+	srv.FailPuts(*failPut)
+	srv.FailGets(*failGet)
+	srv.PresignTTL(*presignTTL)
+
+	fmt.Printf("Fake Poseidon server listening on %s\n", srv.URL)
+	fmt.Printf("Run the bot with: -poseidon-url %s\n", srv.URL)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+}
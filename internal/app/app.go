@@ -2,43 +2,93 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
 
 	"github.com/widiskel/poseidon-voice-bot/internal/app/worker"
+	"github.com/widiskel/poseidon-voice-bot/internal/client/breaker"
+	"github.com/widiskel/poseidon-voice-bot/internal/client/transport"
+	"github.com/widiskel/poseidon-voice-bot/internal/client/tts"
+	"github.com/widiskel/poseidon-voice-bot/internal/config"
+	"github.com/widiskel/poseidon-voice-bot/internal/fakeserver"
 	"github.com/widiskel/poseidon-voice-bot/internal/integrations/gmail"
+	"github.com/widiskel/poseidon-voice-bot/internal/ledger"
 	"github.com/widiskel/poseidon-voice-bot/internal/model"
+	"github.com/widiskel/poseidon-voice-bot/internal/outbox"
+	"github.com/widiskel/poseidon-voice-bot/internal/report"
+	"github.com/widiskel/poseidon-voice-bot/internal/review"
 	"github.com/widiskel/poseidon-voice-bot/internal/utils"
 )
 
-type App struct{}
+type App struct {
+	cfg *config.Config
+}
+
+func (app *App) deps() (worker.Deps, func(), error) {
+	l, err := ledger.Open(app.cfg.Paths.Ledger)
+	if err != nil {
+		return worker.Deps{}, nil, err
+	}
+	deps := worker.Deps{
+		Config: app.cfg,
+		Ledger: l,
+		Review: review.NewStore(app.cfg.Paths.Review),
+		Outbox: outbox.NewStore(app.cfg.Paths.Outbox),
+	}
+	if app.cfg.Audio.Transcoder == config.TranscoderFake {
+		deps.Transcoder = &tts.FakeTranscoder{}
+	} else {
+		deps.Transcoder = tts.FFmpeg{Binary: app.cfg.Audio.FFmpeg, Probe: app.cfg.Audio.FFprobe}
+	}
+	return deps, func() { l.Close() }, nil
+}
 
-func New() *App { return &App{} }
+func New(cfg *config.Config) *App {
+	utils.SetTokenDir(cfg.Paths.TokenDir)
+	transport.Configure(transport.Options{
+		DialTimeout:           cfg.HTTP.DialTimeout.Std(),
+		TLSHandshakeTimeout:   cfg.HTTP.TLSHandshakeTimeout.Std(),
+		ResponseHeaderTimeout: cfg.HTTP.ResponseHeaderTimeout.Std(),
+		IdleConnTimeout:       cfg.HTTP.IdleConnTimeout.Std(),
+	})
+	breaker.Configure(cfg.HTTP.BreakerThreshold, cfg.HTTP.BreakerCooldown.Std())
+	return &App{cfg: cfg}
+}
 
-func (app *App) Run() error {
-	accounts, err := utils.LoadAccounts("accounts/accounts.json")
+func (app *App) Run(ctx context.Context) error {
+	if app.cfg.TestMode {
+		stop, err := app.startTestMode()
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+	if err := app.cfg.CheckSource(); err != nil {
+		return err
+	}
+
+	deps, closeDeps, err := app.deps()
 	if err != nil {
 		return err
 	}
+	defer closeDeps()
 
-	if err := setupGmailTokens(accounts); err != nil {
+	sessions, err := app.loadSessions(ctx)
+	if err != nil {
 		return err
 	}
 
 	var wg sync.WaitGroup
-	wg.Add(len(accounts))
+	wg.Add(len(sessions))
 
-	for idx, email := range accounts {
-		sess := &model.Session{
-			AccIdx: idx,
-			Email:  email,
-			Point:  0,
-		}
+	for _, sess := range sessions {
 		go func(s *model.Session) {
 			defer wg.Done()
-			worker.Run(s)
+			worker.Run(ctx, deps, s)
 		}(sess)
 	}
 
@@ -46,24 +96,197 @@ func (app *App) Run() error {
 	return nil
 }
 
-func setupGmailTokens(emails []string) error {
-	for _, email := range emails {
-		tokenPath := filepath.Join("accounts", fmt.Sprintf("%s-data.json", email))
+// DryRun plans one cycle for every account, stopping before any upload, and writes the plan to w.
+func (app *App) DryRun(ctx context.Context, w io.Writer) error {
+	if app.cfg.TestMode {
+		stop, err := app.startTestMode()
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+
+	deps, closeDeps, err := app.deps()
+	if err != nil {
+		return err
+	}
+	defer closeDeps()
+
+	sessions, err := app.loadSessions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	plans := make([]worker.Plan, len(sessions))
+	wg.Add(len(sessions))
+	for i, sess := range sessions {
+		go func(i int, s *model.Session) {
+			defer wg.Done()
+			plans[i] = worker.DryRun(ctx, deps, s)
+		}(i, sess)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return worker.WritePlans(w, plans)
+}
+
+// startTestMode points the bot at an in-process stand-in server and keeps the ledger, review state and
+// outbox in a temporary directory, so nothing from the run mixes with real submissions.
+func (app *App) startTestMode() (func(), error) {
+	dir, err := os.MkdirTemp("", "poseidon-test-*")
+	if err != nil {
+		return nil, fmt.Errorf("test mode: %w", err)
+	}
+	srv := fakeserver.New()
+	app.cfg.Endpoints = srv.Endpoints()
+	app.cfg.Paths.Ledger = filepath.Join(dir, "ledger.jsonl")
+	app.cfg.Paths.Review = filepath.Join(dir, "review.json")
+	app.cfg.Paths.Outbox = filepath.Join(dir, "outbox")
+	return func() {
+		srv.Close()
+		os.RemoveAll(dir)
+	}, nil
+}
+
+func (app *App) Export(ctx context.Context) error {
+	return app.forEachSession(ctx, func(deps worker.Deps, s *model.Session) error {
+		return worker.Export(ctx, deps, s, filepath.Join(app.cfg.Paths.Sessions, s.Email))
+	})
+}
+
+func (app *App) Submit(ctx context.Context) error {
+	return app.forEachSession(ctx, func(deps worker.Deps, s *model.Session) error {
+		return worker.Submit(ctx, deps, s, filepath.Join(app.cfg.Paths.Sessions, s.Email))
+	})
+}
+
+func (app *App) Report(w io.Writer, format string, opts report.Options) error {
+	entries, err := ledger.Read(app.cfg.Paths.Ledger)
+	if err != nil {
+		return err
+	}
+	return report.Write(w, report.Build(entries, opts), format)
+}
+
+func (app *App) Paused() ([]review.Pause, error) {
+	return review.NewStore(app.cfg.Paths.Review).List()
+}
+
+func (app *App) Resume(account, campaignID string) (int, error) {
+	return review.NewStore(app.cfg.Paths.Review).Resume(account, campaignID)
+}
+
+func (app *App) Outbox() *outbox.Store {
+	return outbox.NewStore(app.cfg.Paths.Outbox)
+}
+
+func (app *App) forEachSession(ctx context.Context, fn func(deps worker.Deps, s *model.Session) error) error {
+	deps, closeDeps, err := app.deps()
+	if err != nil {
+		return err
+	}
+	defer closeDeps()
+
+	sessions, err := app.loadSessions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(sessions))
+	wg.Add(len(sessions))
+
+	for i, sess := range sessions {
+		go func(i int, s *model.Session) {
+			defer wg.Done()
+			if err := fn(deps, s); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", s.Email, err)
+			}
+		}(i, sess)
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (app *App) loadSessions(ctx context.Context) ([]*model.Session, error) {
+	accounts, err := utils.LoadAccounts(app.cfg.Paths.Accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := make([]model.AccountSettings, 0, len(accounts))
+	for _, acc := range accounts {
+		if acc.IsEnabled() {
+			enabled = append(enabled, acc)
+		}
+	}
+	if len(enabled) == 0 {
+		return nil, fmt.Errorf("no enabled accounts in %s", app.cfg.Paths.Accounts)
+	}
+
+	// The stand-in server accepts any bearer token, so test mode skips the Gmail and Dynamic Auth login.
+	if !app.cfg.TestMode {
+		if err := app.setupGmailTokens(ctx, enabled); err != nil {
+			return nil, err
+		}
+	}
+
+	sessions := make([]*model.Session, 0, len(enabled))
+	for idx, acc := range enabled {
+		sessions = append(sessions, &model.Session{
+			AccIdx:          idx,
+			Email:           acc.Email,
+			Point:           0,
+			Account:         acc,
+			Endpoints:       app.cfg.Endpoints,
+			Source:          app.cfg.Audio.Source,
+			RecordingsDir:   app.recordingsDir(acc),
+			CredentialsPath: app.cfg.Paths.Credentials,
+			GmailTokenPath:  app.gmailTokenPath(acc),
+		})
+		if app.cfg.TestMode {
+			sessions[idx].JWT = "test-mode"
+		}
+	}
+	return sessions, nil
+}
+
+func (app *App) recordingsDir(acc model.AccountSettings) string {
+	if acc.RecordingsDir != "" {
+		return acc.RecordingsDir
+	}
+	return filepath.Join(app.cfg.Paths.Recordings, acc.Email)
+}
+
+func (app *App) gmailTokenPath(acc model.AccountSettings) string {
+	if acc.GmailTokenPath != "" {
+		return acc.GmailTokenPath
+	}
+	return app.cfg.GmailTokenPath(acc.Email)
+}
+
+func (app *App) setupGmailTokens(ctx context.Context, accounts []model.AccountSettings) error {
+	for _, acc := range accounts {
+		tokenPath := app.gmailTokenPath(acc)
 		if _, err := os.Stat(tokenPath); err == nil {
 			continue
 		}
 
-		_, err := gmail.NewService(context.Background(), "configs/credentials.json", tokenPath, email)
+		_, err := gmail.NewService(ctx, app.cfg.Paths.Credentials, tokenPath, acc.Email)
 
 		if err != nil {
-			return fmt.Errorf("gmail oauth for %s failed: %w", email, err)
+			return fmt.Errorf("gmail oauth for %s failed: %w", acc.Email, err)
 		}
 	}
 
-	for _, email := range emails {
-		tokenPath := filepath.Join("accounts", fmt.Sprintf("%s-data.json", email))
-		if _, err := os.Stat(tokenPath); err != nil {
-			return fmt.Errorf("missing token for %s after setup", email)
+	for _, acc := range accounts {
+		if _, err := os.Stat(app.gmailTokenPath(acc)); err != nil {
+			return fmt.Errorf("missing token for %s after setup", acc.Email)
 		}
 	}
 	return nil
@@ -0,0 +1,18 @@
+package worker
+
+import (
+	"github.com/widiskel/poseidon-voice-bot/internal/client/tts"
+	"github.com/widiskel/poseidon-voice-bot/internal/config"
+	"github.com/widiskel/poseidon-voice-bot/internal/ledger"
+	"github.com/widiskel/poseidon-voice-bot/internal/outbox"
+	"github.com/widiskel/poseidon-voice-bot/internal/review"
+)
+
+type Deps struct {
+	Config *config.Config
+	Ledger *ledger.Ledger
+	Review *review.Store
+	Outbox *outbox.Store
+	// Transcoder converts and inspects audio; tts.FFmpeg in production.
+	Transcoder tts.Transcoder
+}
@@ -0,0 +1,32 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrDuplicateAudio = errors.New("audio already submitted")
+
+// ErrUploadInFlight means the same audio is still pending in the outbox and will be finished from there.
+var ErrUploadInFlight = errors.New("audio is already being uploaded")
+
+func (op *Operation) checkDuplicate(sha256 string) error {
+	pending, err := op.outbox.Pending("")
+	if err != nil {
+		return fmt.Errorf("read outbox: %w", err)
+	}
+	for _, it := range pending {
+		if it.SHA256 == sha256 {
+			return fmt.Errorf("%w as file %s for %s", ErrUploadInFlight, it.FileID, it.CampaignName)
+		}
+	}
+
+	prev, ok := op.ledger.FindSHA256(sha256)
+	if !ok {
+		return nil
+	}
+	return fmt.Errorf("%w as file %s by %s to %s (script %s) on %s",
+		ErrDuplicateAudio, prev.FileID, prev.Account, prev.CampaignName, prev.ScriptID,
+		prev.SubmittedAt.Local().Format(time.DateTime))
+}
@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/config"
+	"github.com/widiskel/poseidon-voice-bot/internal/utils/exception"
+)
+
+// handleError classifies err and carries out the action configured for its class: waiting before a
+// retry, dropping the JWT, or sleeping until the next cycle. It returns the action so Run knows where
+// to pick up. Outside the campaign loop there is no campaign to skip, so skip_campaign pauses the account.
+func (op *Operation) handleError(ctx context.Context, err error, inCampaign bool) string {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return config.ActionStop
+	}
+
+	ce := exception.Classify(err)
+	action := op.cfg.Errors.Action(string(ce.Class))
+	op.log.JustLog(fmt.Sprintf("%s error: %s", ce.Class, ce.Message))
+	if action == config.ActionSkipCampaign && !inCampaign {
+		op.log.JustLog(fmt.Sprintf("No campaign to skip for %s, pausing the account instead.", ce.Class))
+		action = config.ActionPauseAccount
+	}
+
+	var waitErr error
+	switch action {
+	case config.ActionRetry:
+		d := max(op.retryDelay(ce.Class), ce.RetryAfter)
+		waitErr = op.log.Wait(ctx, "Retrying after "+d.String()+"…", d)
+	case config.ActionRelogin:
+		op.ResetJWT()
+		waitErr = op.log.Wait(ctx, "Session expired. Signing in again…", op.cfg.Retry.Unauthorized.Std())
+	case config.ActionSkipCampaign:
+		op.log.JustLog("Skipping campaign for this cycle.")
+	case config.ActionPauseAccount:
+		waitErr = op.log.Wait(ctx, "Pausing account until the next cycle…", op.cfg.Loop.Interval.Std())
+	case config.ActionStop:
+		op.log.Log("Stopping account: "+ce.Message, 0)
+	}
+	if waitErr != nil {
+		return config.ActionStop
+	}
+	return action
+}
+
+func (op *Operation) retryDelay(class exception.Class) time.Duration {
+	r := op.cfg.Retry
+	switch class {
+	case exception.ClassAuthExpired:
+		return r.Unauthorized.Std()
+	case exception.ClassRateLimited:
+		return r.RateLimited.Std()
+	case exception.ClassQuotaExhausted:
+		return r.Forbidden.Std()
+	case exception.ClassNotFound:
+		return r.NotFound.Std()
+	case exception.ClassServer:
+		return r.ServerError.Std()
+	case exception.ClassTransport, exception.ClassCorruptUpload:
+		return r.Transport.Std()
+	}
+	return r.ClientError.Std()
+}
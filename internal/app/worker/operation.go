@@ -1,33 +1,68 @@
 package worker
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/widiskel/poseidon-voice-bot/internal/client/apiclient"
+	"github.com/widiskel/poseidon-voice-bot/internal/client/poseidon"
+	"github.com/widiskel/poseidon-voice-bot/internal/client/transport"
 	"github.com/widiskel/poseidon-voice-bot/internal/client/tts"
+	"github.com/widiskel/poseidon-voice-bot/internal/config"
 	"github.com/widiskel/poseidon-voice-bot/internal/integrations/dynamic"
+	"github.com/widiskel/poseidon-voice-bot/internal/ledger"
 	"github.com/widiskel/poseidon-voice-bot/internal/model"
+	"github.com/widiskel/poseidon-voice-bot/internal/outbox"
+	"github.com/widiskel/poseidon-voice-bot/internal/recording"
+	"github.com/widiskel/poseidon-voice-bot/internal/review"
 	"github.com/widiskel/poseidon-voice-bot/internal/utils"
-	"github.com/widiskel/poseidon-voice-bot/internal/utils/fingerprint"
 	"github.com/widiskel/poseidon-voice-bot/internal/utils/logger"
 )
 
+const campaignPageSize = 100
+
 type Operation struct {
+	cfg          *config.Config
+	ledger       *ledger.Ledger
+	review       *review.Store
+	outbox       *outbox.Store
+	transcoder   tts.Transcoder
 	session      *model.Session
 	api          *apiclient.ApiClient
+	client       *poseidon.Client
+	uploadHTTP   *http.Client
 	log          *logger.ClassLogger
 	signedIn     bool
+	dryRun       bool
+	timeouts     map[string]time.Time
 	UserInfo     model.UserInfo
 	CampaignList model.Paginate[model.Campaign]
 }
 
-func NewOperation(session *model.Session) *Operation {
+func NewOperation(deps Deps, session *model.Session) *Operation {
+	api := apiclient.New(session, deps.Config.HTTP.Timeout.Std(), apiclient.RetryPolicy{
+		Attempts:  deps.Config.Retry.Attempts,
+		BaseDelay: deps.Config.Retry.BaseDelay.Std(),
+		MaxDelay:  deps.Config.Retry.MaxDelay.Std(),
+	})
 	return &Operation{
-		session: session,
-		api:     apiclient.New(session),
-		log:     logger.NewNamed(fmt.Sprintf("Operation - Account %d", session.AccIdx+1), session),
+		cfg:        deps.Config,
+		ledger:     deps.Ledger,
+		review:     deps.Review,
+		outbox:     deps.Outbox,
+		transcoder: deps.Transcoder,
+		session:    session,
+		api:        api,
+		client:     poseidon.New(api, session),
+		uploadHTTP: &http.Client{Transport: transport.Shared()},
+		timeouts:   map[string]time.Time{},
+		log:        logger.NewNamed(fmt.Sprintf("Operation - Account %d", session.AccIdx+1), session),
 	}
 }
 
@@ -39,16 +74,7 @@ func (op *Operation) ResetJWT() {
 	op.signedIn = false
 }
 
-func (op *Operation) buildCommonHeaders() map[string]string {
-	return map[string]string{
-		"Authorization":            "Bearer " + op.session.JWT,
-		"Origin":                   "https://app.psdn.ai",
-		"Referer":                  "https://app.psdn.ai/",
-		"X-Fingerprint-Request-Id": fingerprint.MakeRequestID(),
-	}
-}
-
-func (op *Operation) LoginIfNeeded() error {
+func (op *Operation) LoginIfNeeded(ctx context.Context) error {
 	if op.session.JWT != "" {
 		return nil
 	}
@@ -67,7 +93,7 @@ func (op *Operation) LoginIfNeeded() error {
 	}
 
 	op.log.Log("Signing in via Dynamic Auth…", 1500)
-	if err := dynamic.SignIn(op.session, op.api); err != nil {
+	if err := dynamic.SignIn(ctx, op.session, op.api); err != nil {
 		return err
 	}
 	op.signedIn = true
@@ -75,21 +101,14 @@ func (op *Operation) LoginIfNeeded() error {
 	return nil
 }
 
-func (op *Operation) GetUserInformation() error {
-	headers := op.buildCommonHeaders()
+func (op *Operation) GetUserInformation(ctx context.Context) error {
 	op.log.Log("Getting user Information...", 1500)
 
-	resp, err := op.api.Call("https://poseidon-depin-server.storyapis.com/users/me", "GET", nil, headers)
+	userInfo, err := op.client.Me(ctx)
 	if err != nil {
 		return err
 	}
 
-	var userInfo model.UserInfo
-	if err := resp.Decode(&userInfo); err != nil {
-		op.log.JustLog("Decode /users/me failed: " + err.Error())
-		return err
-	}
-
 	op.session.Point = userInfo.Points
 	op.session.ID = userInfo.ID
 	if op.session.Email == "" {
@@ -97,135 +116,307 @@ func (op *Operation) GetUserInformation() error {
 	}
 	op.UserInfo = userInfo
 	op.log.Log("Successfully Get User Information")
+	op.recordSnapshot(userInfo)
+	op.refreshReview()
 	return nil
 }
 
-func (op *Operation) GetCampaign() error {
-	headers := op.buildCommonHeaders()
-	op.log.Log("Getting Available Campaign...", 1500)
-
-	resp, err := op.api.Call("https://poseidon-depin-server.storyapis.com/campaigns?page=1&size=100", "GET", nil, headers)
+func (op *Operation) recordSnapshot(u model.UserInfo) {
+	if op.dryRun {
+		return
+	}
+	err := op.ledger.Append(ledger.Entry{
+		Type:    ledger.TypeSnapshot,
+		Account: op.session.Email,
+		Points:  u.Points,
+		Rank:    u.CurrentRank,
+	})
 	if err != nil {
-		return err
+		op.log.JustLog("Failed to record account snapshot in ledger: " + err.Error())
 	}
+}
+
+func (op *Operation) GetCampaign(ctx context.Context) error {
+	op.log.Log("Getting Available Campaign...", 1500)
 
 	var list model.Paginate[model.Campaign]
-	if err := resp.Decode(&list); err != nil {
-		op.log.JustLog("Decode /campaigns failed: " + err.Error())
-		return err
+	for page := 1; ; page++ {
+		res, err := op.client.ListCampaigns(ctx, page, campaignPageSize)
+		if err != nil {
+			return err
+		}
+		list.Items = append(list.Items, res.Items...)
+		list.Total, list.Pages, list.Size = res.Total, res.Pages, res.Size
+		if len(res.Items) == 0 || page >= res.Pages {
+			break
+		}
 	}
+	list.Page = 1
 
 	op.CampaignList = list
-	op.log.Log("Successfully Get Available Campaign")
+	op.log.Log(fmt.Sprintf("Successfully Get Available Campaign (%d)", len(list.Items)))
 	return nil
 }
 
-func (op *Operation) CheckCampaignAccess(c model.Campaign) (bool, error) {
-	headers := op.buildCommonHeaders()
+func (op *Operation) CheckCampaignAccess(ctx context.Context, c model.Campaign) (model.Access, error) {
 	op.log.Log(fmt.Sprintf("Checking Access For Campaign %s...", c.CampaignName), 1500)
 
-	resp, err := op.api.Call(
-		fmt.Sprintf("https://poseidon-depin-server.storyapis.com/campaigns/%s/access", c.VirtualID),
-		"GET", nil, headers,
-	)
+	access, err := op.client.CampaignAccess(ctx, c.VirtualID)
 	if err != nil {
-		return false, err
+		return model.Access{}, err
 	}
 
-	var access model.Access
-	if err := resp.Decode(&access); err != nil {
-		op.log.JustLog("Decode /access failed: " + err.Error())
-		return false, err
+	if until, ok := access.TimeoutTime(); ok && time.Now().Before(until) {
+		op.timeouts[c.VirtualID] = until
+		access.Allowed = false
+		if access.Reason == "" {
+			access.Reason = "timed out until " + until.Local().Format(time.DateTime)
+		}
+	} else {
+		delete(op.timeouts, c.VirtualID)
+	}
+
+	op.session.Campaign = c.CampaignName
+	op.session.Cap = access.Cap
+	op.session.UsedToday = access.UsedToday
+	return access, nil
+}
+
+func (op *Operation) TimedOutUntil(c model.Campaign) (time.Time, bool) {
+	until, ok := op.timeouts[c.VirtualID]
+	if !ok || !time.Now().Before(until) {
+		return time.Time{}, false
 	}
-	return access.Allowed, nil
+	return until, true
 }
 
-func (op *Operation) ProcessCampaign(c model.Campaign) error {
-	headers := op.buildCommonHeaders()
+func (op *Operation) ProcessCampaignQuota(ctx context.Context, c model.Campaign, lang string, access model.Access) error {
+	budget := access.Remaining
+	if budget <= 0 {
+		if access.Cap > 0 {
+			op.log.JustLog(fmt.Sprintf("Daily cap reached for %s (%d/%d)", c.CampaignName, access.UsedToday, access.Cap))
+			return nil
+		}
+		budget = 1
+	}
+
+	for i := 0; i < budget; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		submitted, err := op.ProcessCampaign(ctx, c, lang)
+		if err != nil {
+			return err
+		}
+		if !submitted {
+			return nil
+		}
+		op.session.UsedToday++
+	}
+	return nil
+}
+
+func (op *Operation) ProcessCampaign(ctx context.Context, c model.Campaign, lang string) (bool, error) {
 	op.log.Log(fmt.Sprintf("Prepairing to Process Campaign %s...", c.CampaignName), 1500)
 
-	resp, err := op.api.Call(
-		fmt.Sprintf("https://poseidon-depin-server.storyapis.com/scripts/next?language_code=%s&campaign_id=%s",
-			c.SupportedLanguages[0], c.VirtualID),
-		"GET", nil, headers,
-	)
+	script, err := op.NextScript(ctx, c, lang)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	var script model.CampaignScript
-	if err := resp.Decode(&script); err != nil {
-		op.log.JustLog("Decode /scripts failed: " + err.Error())
-		return err
+	webmPath, cleanup, err := op.prepareAudio(ctx, c, lang, script)
+	if err != nil {
+		if errors.Is(err, recording.ErrNotFound) {
+			return false, op.queueForRecording(c, lang, script, "")
+		}
+		var qe *QualityError
+		if errors.As(err, &qe) {
+			op.log.Log(fmt.Sprintf("Rejected %s: %s", filepath.Base(qe.Path), strings.Join(qe.Reasons, "; ")), 3000)
+			if err := recording.MarkRejected(qe.Path); err != nil {
+				op.log.JustLog("Failed to move rejected recording: " + err.Error())
+			}
+			return false, op.queueForRecording(c, lang, script, "rejected: "+strings.Join(qe.Reasons, "; "))
+		}
+		return false, err
+	}
+	defer cleanup()
+
+	job := uploadJob{
+		Campaign:     c,
+		ScriptID:     script.Script.ID,
+		AssignmentID: script.AssignmentID,
+		Language:     lang,
+		Path:         webmPath,
+	}
+	_, err = op.UploadAudio(ctx, job)
+	if errors.Is(err, ErrDuplicateAudio) {
+		op.log.JustLog(err.Error())
+		if op.session.Source == model.SourceRecording {
+			op.setAsideDuplicate(lang, script)
+		}
+		return false, nil
+	}
+	if errors.Is(err, ErrUploadInFlight) {
+		op.log.JustLog("Skipping campaign until the outbox finishes: " + err.Error())
+		return false, nil
+	}
+	if err != nil && !errors.Is(err, ErrNeedsReview) {
+		return false, err
 	}
 
-	webmPath, err := tts.SynthesizeToWebM(op.session, script.Script.Content, tts.Options{
-		Language: script.Script.Language.Code,
-		Bitrate:  "48k",
-	})
-	if err != nil {
-		return fmt.Errorf("tts synth: %w", err)
+	if op.session.Source == model.SourceRecording {
+		op.finishRecording(lang, script.Script.ID, script.AssignmentID)
 	}
-	defer os.Remove(webmPath)
+	return true, err
+}
 
-	fileName := fmt.Sprintf("audio_recording_%d.webm", time.Now().UnixMilli())
+func (op *Operation) NextScript(ctx context.Context, c model.Campaign, lang string) (model.CampaignScript, error) {
+	return op.client.NextScript(ctx, lang, c.VirtualID)
+}
 
-	initBody := model.FileUploadRequest{
-		ContentType:        "audio/webm",
-		FileName:           fileName,
-		ScriptAssignmentID: script.AssignmentID,
+func (op *Operation) prepareAudio(ctx context.Context, c model.Campaign, lang string, script model.CampaignScript) (string, func(), error) {
+	if op.session.Source != model.SourceRecording {
+		webmPath, err := tts.SynthesizeToWebM(ctx, op.session, script.Script.Content, tts.Options{
+			Language:   script.Script.Language.Code,
+			Transcoder: op.transcoder,
+			Encode:     op.encodeOptions(),
+		})
+		if err != nil {
+			return "", nil, fmt.Errorf("tts synth: %w", err)
+		}
+		return webmPath, func() { os.RemoveAll(filepath.Dir(webmPath)) }, nil
 	}
-	respInit, err := op.api.Call(
-		fmt.Sprintf("https://poseidon-depin-server.storyapis.com/files/uploads/%s", c.VirtualID),
-		"POST", initBody, headers,
-	)
+
+	recPath, err := recording.FindForLanguage(op.session.RecordingsDir, lang, script.Script.ID, script.AssignmentID)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
+	op.log.Log(fmt.Sprintf("Found recording %s for campaign %s", filepath.Base(recPath), c.CampaignName), 800)
+	return op.encodeRecording(ctx, recPath, scriptText(script))
+}
 
-	var up model.FileUploadResponse
-	if err := respInit.Decode(&up); err != nil {
-		op.log.JustLog("Decode init upload failed: " + err.Error())
-		return err
+// encodeRecording runs the clean-up chain, the quality checks and the WebM conversion on a recording.
+// Quality is measured on the processed audio, since that is what gets uploaded.
+func (op *Operation) encodeRecording(ctx context.Context, recPath, script string) (string, func(), error) {
+	src := recPath
+	processed := recording.ProcessedPath(recPath)
+	ran, err := tts.Preprocess(ctx, op.transcoder, recPath, processed, op.preprocessOptions())
+	if err != nil {
+		return "", nil, err
+	}
+	if ran {
+		op.log.Log(fmt.Sprintf("Preprocessed %s -> %s", filepath.Base(recPath), filepath.Base(processed)), 500)
+		src = processed
 	}
 
-	if err := tts.PutPresignedWebM(up.PresignedURL, webmPath); err != nil {
-		return err
+	if err := op.checkQuality(ctx, src, script); err != nil {
+		var qe *QualityError
+		if errors.As(err, &qe) {
+			qe.Path = recPath
+		}
+		return "", nil, err
 	}
+	return op.recordingToWebM(ctx, src)
+}
 
-	dg, err := tts.ComputeSHA256AndSize(webmPath)
+func (op *Operation) recordingToWebM(ctx context.Context, recPath string) (string, func(), error) {
+	format, err := op.transcoder.Detect(ctx, recPath)
 	if err != nil {
-		return err
+		return "", nil, fmt.Errorf("detect recording format: %w", err)
 	}
-
-	validateBody := model.FileUploadValidationRequest{
-		ContentType: "audio/webm",
-		ObjectKey:   up.ObjectKey,
-		Sha256Hash:  dg.HashHex,
-		Filesize:    dg.FileSize,
-		FileName:    fileName,
-		VirtualID:   up.FileID,
-		CampaignID:  c.VirtualID,
+	if format == tts.FormatWebM {
+		return recPath, func() {}, nil
 	}
+	op.log.Debug(fmt.Sprintf("Recording %s is %s, converting to WebM/Opus", filepath.Base(recPath), format))
 
-	respVal, err := op.api.Call(
-		"https://poseidon-depin-server.storyapis.com/files",
-		"POST", validateBody, headers,
-	)
+	webmPath, err := tts.ConvertToWebM(ctx, op.session, recPath, tts.Options{
+		Transcoder: op.transcoder,
+		Encode:     op.encodeOptions(),
+	})
 	if err != nil {
-		return err
+		return "", nil, fmt.Errorf("convert recording: %w", err)
+	}
+	return webmPath, func() { os.RemoveAll(filepath.Dir(webmPath)) }, nil
+}
+
+func (op *Operation) encodeOptions() tts.EncodeOptions {
+	return tts.EncodeOptions{
+		Bitrate:    op.cfg.Audio.Bitrate,
+		SampleRate: op.cfg.Audio.SampleRate,
+		Mono:       op.cfg.Audio.Mono,
 	}
+}
 
-	var val model.FileUploadValidationResponse
-	if err := respVal.Decode(&val); err != nil {
-		op.log.JustLog("Decode validation failed: " + err.Error())
-		return err
+func (op *Operation) preprocessOptions() tts.PreprocessOptions {
+	p := op.cfg.Preprocess
+	opts := tts.PreprocessOptions{
+		TrimSilence: p.TrimSilence,
+		SilenceDB:   p.SilenceDB,
+		Loudnorm:    p.Loudnorm,
+		TargetLUFS:  p.TargetLUFS,
+	}
+	if p.HighPass {
+		opts.HighPassHz = p.HighPassHz
+	}
+	if p.Resample {
+		opts.SampleRate = p.SampleRate
 	}
+	return opts
+}
 
-	if val.FileStatus != "UPLOADED" {
-		return fmt.Errorf("file status unexpected: %s", val.FileStatus)
+func scriptText(script model.CampaignScript) string {
+	if romanized, _ := script.Script.RomanizedContent.(string); romanized != "" {
+		return romanized
 	}
+	return script.Script.Content
+}
 
-	op.log.Log(fmt.Sprintf("Upload validated. Awarded=%d verified=%v", val.PointsAwarded, val.IsVerifiedQuality), 1200)
+func (op *Operation) queueForRecording(c model.Campaign, lang string, script model.CampaignScript, note string) error {
+	romanized, _ := script.Script.RomanizedContent.(string)
+	added, err := recording.Enqueue(op.session.RecordingsDir, recording.QueueItem{
+		ScriptID:         script.Script.ID,
+		AssignmentID:     script.AssignmentID,
+		CampaignID:       c.VirtualID,
+		CampaignName:     c.CampaignName,
+		Language:         lang,
+		Content:          script.Script.Content,
+		RomanizedContent: romanized,
+		Note:             note,
+	})
+	if err != nil {
+		return fmt.Errorf("queue script for recording: %w", err)
+	}
+	switch {
+	case note != "":
+		op.log.Log(fmt.Sprintf("Script %s needs a new recording. Noted in %s", script.Script.ID, recording.QueuePath(op.session.RecordingsDir)), 1500)
+	case added:
+		op.log.Log(fmt.Sprintf("No recording for script %s. Added to %s", script.Script.ID, recording.QueuePath(op.session.RecordingsDir)), 1500)
+	default:
+		op.log.Log(fmt.Sprintf("Script %s is still waiting for a recording", script.Script.ID), 800)
+	}
 	return nil
 }
+
+// setAsideDuplicate moves the recording out of the way so the script is queued for a fresh take next cycle.
+func (op *Operation) setAsideDuplicate(lang string, script model.CampaignScript) {
+	recPath, err := recording.FindForLanguage(op.session.RecordingsDir, lang, script.Script.ID, script.AssignmentID)
+	if err != nil {
+		return
+	}
+	if err := recording.MarkDuplicate(recPath); err != nil {
+		op.log.JustLog("Failed to move duplicate recording: " + err.Error())
+	}
+}
+
+func (op *Operation) finishRecording(lang, scriptID, assignmentID string) {
+	if err := recording.Dequeue(op.session.RecordingsDir, scriptID, assignmentID); err != nil {
+		op.log.JustLog("Failed to update record queue: " + err.Error())
+	}
+	recPath, err := recording.FindForLanguage(op.session.RecordingsDir, lang, scriptID, assignmentID)
+	if err != nil {
+		return
+	}
+	if err := recording.MarkSubmitted(recPath); err != nil {
+		op.log.JustLog("Failed to move submitted recording: " + err.Error())
+	}
+}
@@ -0,0 +1,169 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/model"
+	"github.com/widiskel/poseidon-voice-bot/internal/recording"
+)
+
+// Plan is what one cycle of Run would do for an account.
+type Plan struct {
+	Account   string
+	Source    string
+	Points    int
+	Languages []string
+	Pending   int
+	Error     string
+	Campaigns []CampaignPlan
+}
+
+type CampaignPlan struct {
+	Name      string
+	Language  string
+	Remaining int
+	ScriptID  string
+	Recording string
+	Submit    bool
+	Reason    string
+}
+
+// DryRun walks one cycle of Run up to the first upload call and reports what would be submitted.
+// It fetches the next script of each campaign but never touches recordings, the outbox or the ledger.
+func DryRun(ctx context.Context, deps Deps, session *model.Session) Plan {
+	op := NewOperation(deps, session)
+	op.dryRun = true
+	plan := Plan{Account: session.Email, Source: session.Source}
+
+	if err := op.LoginIfNeeded(ctx); err != nil {
+		plan.Error = "login: " + err.Error()
+		return plan
+	}
+	if err := op.GetUserInformation(ctx); err != nil {
+		plan.Error = "user information: " + err.Error()
+		return plan
+	}
+	plan.Account = session.Email
+	plan.Points = op.UserInfo.Points
+	plan.Languages = op.spokenLanguages()
+	if pending, err := op.outbox.Pending(session.Email); err == nil {
+		plan.Pending = len(pending)
+	}
+
+	if err := op.GetCampaign(ctx); err != nil {
+		plan.Error = "campaigns: " + err.Error()
+		return plan
+	}
+
+	for _, c := range op.CampaignList.Items {
+		if ctx.Err() != nil {
+			break
+		}
+		plan.Campaigns = append(plan.Campaigns, op.planCampaign(ctx, c))
+	}
+	return plan
+}
+
+func (op *Operation) planCampaign(ctx context.Context, c model.Campaign) CampaignPlan {
+	cp := CampaignPlan{Name: c.CampaignName}
+
+	lang, access, reason, err := op.selectCampaign(ctx, c)
+	if err != nil {
+		cp.Reason = "access check failed: " + err.Error()
+		return cp
+	}
+	if reason != "" {
+		cp.Reason = reason
+		return cp
+	}
+	cp.Language = lang
+	cp.Remaining = access.Remaining
+	if access.Remaining <= 0 && access.Cap > 0 {
+		cp.Reason = fmt.Sprintf("daily cap reached (%d/%d)", access.UsedToday, access.Cap)
+		return cp
+	}
+
+	script, err := op.NextScript(ctx, c, lang)
+	if err != nil {
+		cp.Reason = "next script: " + err.Error()
+		return cp
+	}
+	cp.ScriptID = script.Script.ID
+
+	if op.session.Source != model.SourceRecording {
+		cp.Submit = true
+		return cp
+	}
+	recPath, err := recording.FindForLanguage(op.session.RecordingsDir, lang, script.Script.ID, script.AssignmentID)
+	if err != nil {
+		if errors.Is(err, recording.ErrNotFound) {
+			cp.Reason = "no recording, script would be queued in " + recording.QueuePath(op.session.RecordingsDir)
+		} else {
+			cp.Reason = err.Error()
+		}
+		return cp
+	}
+	cp.Recording = recPath
+	cp.Submit = true
+	return cp
+}
+
+func WritePlans(w io.Writer, plans []Plan) error {
+	for i, p := range plans {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Account %s (source %s", p.Account, p.Source)
+		if p.Error != "" {
+			fmt.Fprintf(w, ")\n  not planned: %s\n", p.Error)
+			continue
+		}
+		languages := strings.Join(p.Languages, ", ")
+		if languages == "" {
+			languages = "none"
+		}
+		fmt.Fprintf(w, ", %d points, languages %s)\n", p.Points, languages)
+		if p.Pending > 0 {
+			fmt.Fprintf(w, "  %d upload(s) waiting in the outbox would be resumed first\n", p.Pending)
+		}
+
+		submit := 0
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  CAMPAIGN\tLANGUAGE\tREMAINING\tSCRIPT\tPLAN")
+		for _, c := range p.Campaigns {
+			action := "skip: " + c.Reason
+			if c.Submit {
+				submit++
+				action = "submit synthesized speech"
+				if c.Recording != "" {
+					action = "submit " + c.Recording
+				}
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", c.Name, dash(c.Language), dash(remaining(c)), dash(c.ScriptID), action)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "  %d of %d campaign(s) would get a submission\n", submit, len(p.Campaigns))
+	}
+	return nil
+}
+
+func remaining(c CampaignPlan) string {
+	if c.Language == "" {
+		return ""
+	}
+	return fmt.Sprint(c.Remaining)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type QualityError struct {
+	Path    string
+	Reasons []string
+}
+
+func (e *QualityError) Error() string {
+	return "recording failed quality checks: " + strings.Join(e.Reasons, "; ")
+}
+
+// checkQuality measures a recording against the configured limits before anything is sent.
+func (op *Operation) checkQuality(ctx context.Context, path, script string) error {
+	q := op.cfg.Quality
+	if !q.Enabled {
+		return nil
+	}
+
+	st, err := op.transcoder.Analyze(ctx, path, q.SilenceDB)
+	if err != nil {
+		return fmt.Errorf("analyze recording: %w", err)
+	}
+	op.log.Debug(fmt.Sprintf("Audio stats for %s: %+v", path, st))
+
+	var reasons []string
+	fail := func(format string, args ...any) {
+		reasons = append(reasons, fmt.Sprintf(format, args...))
+	}
+
+	if q.MinDuration > 0 && st.Duration < q.MinDuration.Std() {
+		fail("too short: %s, need at least %s", round(st.Duration), q.MinDuration)
+	}
+	if q.MaxDuration > 0 && st.Duration > q.MaxDuration.Std() {
+		fail("too long: %s, at most %s", round(st.Duration), q.MaxDuration)
+	}
+	if q.MinSampleRate > 0 && st.SampleRate < q.MinSampleRate {
+		fail("sample rate %d Hz is below %d Hz", st.SampleRate, q.MinSampleRate)
+	}
+	if q.MaxChannels > 0 && st.Channels > q.MaxChannels {
+		fail("%d channels, at most %d", st.Channels, q.MaxChannels)
+	}
+	if q.MinRMSdB != 0 && st.RMSdB < q.MinRMSdB {
+		fail("too quiet: RMS %.1f dB, need at least %.1f dB", st.RMSdB, q.MinRMSdB)
+	}
+	if q.MaxClippingRatio > 0 && st.ClippingRatio > q.MaxClippingRatio {
+		fail("clipping on %.2f%% of samples, at most %.2f%%", st.ClippingRatio*100, q.MaxClippingRatio*100)
+	}
+	if q.MaxLeadingSilence > 0 && st.LeadingSilence > q.MaxLeadingSilence.Std() {
+		fail("%s of silence at the start, at most %s", round(st.LeadingSilence), q.MaxLeadingSilence)
+	}
+	if q.MaxTrailingSilence > 0 && st.TrailingSilence > q.MaxTrailingSilence.Std() {
+		fail("%s of silence at the end, at most %s", round(st.TrailingSilence), q.MaxTrailingSilence)
+	}
+
+	speech := st.Duration - st.LeadingSilence - st.TrailingSilence
+	if words := len(strings.Fields(script)); words > 0 && speech > 0 {
+		wps := float64(words) / speech.Seconds()
+		if q.MinWordsPerSecond > 0 && wps < q.MinWordsPerSecond {
+			fail("%.1f words per second is slower than %.1f for %d words of script", wps, q.MinWordsPerSecond, words)
+		}
+		if q.MaxWordsPerSecond > 0 && wps > q.MaxWordsPerSecond {
+			fail("%.1f words per second is faster than %.1f for %d words of script", wps, q.MaxWordsPerSecond, words)
+		}
+	}
+
+	if len(reasons) > 0 {
+		return &QualityError{Path: path, Reasons: reasons}
+	}
+	return nil
+}
+
+func round(d time.Duration) time.Duration {
+	return d.Round(100 * time.Millisecond)
+}
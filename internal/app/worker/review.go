@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/model"
+	"github.com/widiskel/poseidon-voice-bot/internal/outbox"
+	"github.com/widiskel/poseidon-voice-bot/internal/review"
+)
+
+var ErrNeedsReview = errors.New("needs review")
+
+func validationFlags(val model.FileUploadValidationResponse) []string {
+	var flags []string
+	if val.IsFlaggedDuplicate {
+		flags = append(flags, "duplicate")
+	}
+	if val.IsFlaggedBot {
+		flags = append(flags, "bot")
+	}
+	if val.IsFlaggedSpam {
+		flags = append(flags, "spam")
+	}
+	return flags
+}
+
+// pauseIfFlagged stops further submissions to the campaign until an operator runs `resume`.
+func (op *Operation) pauseIfFlagged(it outbox.Item, val model.FileUploadValidationResponse) error {
+	flags := validationFlags(val)
+	if len(flags) == 0 {
+		return nil
+	}
+
+	p := review.Pause{
+		Account:      it.Account,
+		CampaignID:   it.CampaignID,
+		CampaignName: it.CampaignName,
+		FileID:       it.FileID,
+		Flags:        flags,
+	}
+	op.session.NeedsReview = true
+	if err := op.review.Pause(p); err != nil {
+		op.log.JustLog("Failed to persist review state: " + err.Error())
+	}
+	return fmt.Errorf("%w: %s %s, submissions paused until resumed", ErrNeedsReview, it.CampaignName, p.Reason())
+}
+
+func (op *Operation) pausedFor(campaignID string) (review.Pause, bool) {
+	p, ok, err := op.review.Paused(op.session.Email, campaignID)
+	if err != nil {
+		// Without readable state we cannot tell what was flagged, so hold everything back.
+		op.log.JustLog("Failed to read review state: " + err.Error())
+		return review.Pause{Flags: []string{"unknown"}}, true
+	}
+	return p, ok
+}
+
+func (op *Operation) refreshReview() {
+	needs, err := op.review.NeedsReview(op.session.Email)
+	if err != nil {
+		op.log.JustLog("Failed to read review state: " + err.Error())
+		return
+	}
+	op.session.NeedsReview = needs
+}
+
+func pausedReason(p review.Pause) string {
+	return "paused for review, " + p.Reason()
+}
@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/model"
+)
+
+// selectCampaign runs the account rules, the language match and the access check for c.
+// A non-empty reason means the campaign is skipped this cycle.
+func (op *Operation) selectCampaign(ctx context.Context, c model.Campaign) (string, model.Access, string, error) {
+	if ok, reason := op.campaignSelected(c); !ok {
+		return "", model.Access{}, reason, nil
+	}
+	lang, reason := op.chooseLanguage(c)
+	if lang == "" {
+		return "", model.Access{}, reason, nil
+	}
+	if until, ok := op.TimedOutUntil(c); ok {
+		return "", model.Access{}, "timed out until " + until.Local().Format(time.DateTime), nil
+	}
+
+	access, err := op.CheckCampaignAccess(ctx, c)
+	if err != nil {
+		return "", model.Access{}, "", err
+	}
+	if !access.Allowed {
+		return "", access, "no access, " + access.Reason, nil
+	}
+	return lang, access, "", nil
+}
+
+func (op *Operation) campaignSelected(c model.Campaign) (bool, string) {
+	sel := op.session.Account.Campaigns
+
+	if !c.IsScripted {
+		return false, "campaign is not scripted"
+	}
+	if p, ok := op.pausedFor(c.VirtualID); ok {
+		return false, pausedReason(p)
+	}
+	if matchesCampaign(sel.Exclude, c) {
+		return false, "excluded by account settings"
+	}
+	if len(sel.Include) > 0 && !matchesCampaign(sel.Include, c) {
+		return false, "not in account include list"
+	}
+	if !sel.IncludeExpired {
+		if end, ok := c.EndTime(); ok && time.Now().After(end) {
+			return false, "campaign ended on " + c.EndDate
+		}
+	}
+	if len(sel.Types) > 0 && !containsFold(sel.Types, c.CampaignType) {
+		return false, "campaign type " + c.CampaignType + " not selected"
+	}
+	if len(sel.Tags) > 0 && !anyFold(sel.Tags, c.Tags) {
+		return false, "no selected tag in " + strings.Join(c.Tags, ", ")
+	}
+	if anyFold(sel.ExcludeTags, c.Tags) {
+		return false, "has excluded tag"
+	}
+	if len(sel.Languages) > 0 && !anyFold(sel.Languages, c.SupportedLanguages) {
+		return false, "no selected language in " + strings.Join(c.SupportedLanguages, ", ")
+	}
+	if len(sel.RegistrationStatus) > 0 && !containsFold(sel.RegistrationStatus, c.RegistrationStatus) {
+		return false, "registration status " + c.RegistrationStatus + " not selected"
+	}
+	return true, ""
+}
+
+func matchesCampaign(list []string, c model.Campaign) bool {
+	return containsFold(list, c.VirtualID) || containsFold(list, c.CampaignName)
+}
+
+func containsFold(list []string, v string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyFold(want, have []string) bool {
+	for _, w := range want {
+		if containsFold(have, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (op *Operation) spokenLanguages() []string {
+	if len(op.session.Account.Languages) > 0 {
+		return op.session.Account.Languages
+	}
+	return op.UserInfo.SpokenLanguages()
+}
+
+func (op *Operation) chooseLanguage(c model.Campaign) (string, string) {
+	if len(c.SupportedLanguages) == 0 {
+		return "", "campaign has no supported languages"
+	}
+
+	spoken := op.spokenLanguages()
+	if len(spoken) == 0 {
+		if op.session.Source == model.SourceTTS {
+			return c.SupportedLanguages[0], ""
+		}
+		return "", "no spoken languages in account settings or profile"
+	}
+
+	for _, match := range []func(a, b string) bool{sameLanguage, sameBaseLanguage} {
+		for _, want := range spoken {
+			for _, have := range c.SupportedLanguages {
+				if match(want, have) {
+					return have, ""
+				}
+			}
+		}
+	}
+	return "", fmt.Sprintf("spoken languages [%s] not in supported [%s]",
+		strings.Join(spoken, ", "), strings.Join(c.SupportedLanguages, ", "))
+}
+
+func normalizeLanguage(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "_", "-"))
+}
+
+func sameLanguage(a, b string) bool {
+	return normalizeLanguage(a) == normalizeLanguage(b)
+}
+
+func sameBaseLanguage(a, b string) bool {
+	base := func(code string) string {
+		code = normalizeLanguage(code)
+		if i := strings.Index(code, "-"); i > 0 {
+			return code[:i]
+		}
+		return code
+	}
+	return base(a) == base(b)
+}
@@ -0,0 +1,316 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/ledger"
+	"github.com/widiskel/poseidon-voice-bot/internal/model"
+	"github.com/widiskel/poseidon-voice-bot/internal/recording"
+	"github.com/widiskel/poseidon-voice-bot/internal/utils/exception"
+)
+
+const manifestFile = "manifest.json"
+
+func Export(ctx context.Context, deps Deps, session *model.Session, dir string) error {
+	op := NewOperation(deps, session)
+
+	if err := op.LoginIfNeeded(ctx); err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	if err := op.GetUserInformation(ctx); err != nil {
+		return fmt.Errorf("user information: %w", err)
+	}
+	if err := op.GetCampaign(ctx); err != nil {
+		return fmt.Errorf("campaigns: %w", err)
+	}
+
+	manifest, err := LoadManifest(dir)
+	if err != nil {
+		return err
+	}
+	if manifest.Email == "" {
+		manifest.Email = session.Email
+		manifest.CreatedAt = time.Now()
+	}
+
+	known := make(map[string]bool, len(manifest.Entries))
+	pending := map[string]int{}
+	for _, e := range manifest.Entries {
+		known[e.AssignmentID] = true
+		if e.SubmittedAt == nil {
+			pending[e.CampaignID]++
+		}
+	}
+
+	added := 0
+	for _, c := range op.CampaignList.Items {
+		if ctx.Err() != nil {
+			break
+		}
+		if ok, reason := op.campaignSelected(c); !ok {
+			op.log.JustLog("Skipping campaign " + c.CampaignName + ": " + reason)
+			continue
+		}
+		lang, reason := op.chooseLanguage(c)
+		if lang == "" {
+			op.log.JustLog("Skipping campaign " + c.CampaignName + ": " + reason)
+			continue
+		}
+		access, err := op.CheckCampaignAccess(ctx, c)
+		if err != nil {
+			op.log.JustLog("Failed to check campaign access: " + err.Error())
+			continue
+		}
+		if !access.Allowed {
+			op.log.JustLog(fmt.Sprintf("No access to campaign %s: %s", c.CampaignName, access.Reason))
+			continue
+		}
+
+		// Export as many scripts as can still be submitted today, less those already waiting in the manifest.
+		budget := access.Remaining
+		if budget <= 0 {
+			if access.Cap > 0 {
+				op.log.JustLog(fmt.Sprintf("Daily cap reached for %s (%d/%d)", c.CampaignName, access.UsedToday, access.Cap))
+				continue
+			}
+			budget = 1
+		}
+		budget -= pending[c.VirtualID]
+
+		for ; budget > 0 && ctx.Err() == nil; budget-- {
+			script, err := op.NextScript(ctx, c, lang)
+			if err != nil {
+				if !errors.Is(exception.Classify(err), exception.ErrNotFound) {
+					op.log.JustLog("Failed to get next script: " + err.Error())
+				}
+				break
+			}
+			if script.AssignmentID == "" {
+				break
+			}
+			// The server hands out the same assignment until it is submitted, so a repeat means no more scripts.
+			if known[script.AssignmentID] {
+				break
+			}
+			known[script.AssignmentID] = true
+
+			romanized, _ := script.Script.RomanizedContent.(string)
+			manifest.Entries = append(manifest.Entries, model.ManifestEntry{
+				ScriptID:         script.Script.ID,
+				AssignmentID:     script.AssignmentID,
+				CampaignID:       c.VirtualID,
+				CampaignName:     c.CampaignName,
+				Language:         lang,
+				Content:          script.Script.Content,
+				RomanizedContent: romanized,
+			})
+			added++
+		}
+	}
+
+	if err := SaveManifest(dir, manifest); err != nil {
+		return err
+	}
+	op.log.Log(fmt.Sprintf("Exported %d new script(s) to %s", added, filepath.Join(dir, manifestFile)), 1200)
+	return nil
+}
+
+func Submit(ctx context.Context, deps Deps, session *model.Session, dir string) error {
+	op := NewOperation(deps, session)
+
+	manifest, err := LoadManifest(dir)
+	if err != nil {
+		return err
+	}
+	if len(manifest.Entries) == 0 {
+		op.log.Log("Manifest has no scripts to submit.", 800)
+		return nil
+	}
+
+	if err := op.LoginIfNeeded(ctx); err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	if err := op.GetUserInformation(ctx); err != nil {
+		return fmt.Errorf("user information: %w", err)
+	}
+	op.resumeOutbox(ctx)
+
+	// Uploads finished from the outbox are in the ledger now; anything still in the outbox is retried there.
+	done := op.submittedAssignments()
+	inFlight := map[string]bool{}
+	if items, err := op.outbox.Pending(session.Email); err == nil {
+		for _, it := range items {
+			inFlight[it.AssignmentID] = true
+		}
+	} else {
+		op.log.JustLog("Failed to read outbox: " + err.Error())
+	}
+	reconciled := 0
+	for i := range manifest.Entries {
+		e := &manifest.Entries[i]
+		if prev, ok := done[e.AssignmentID]; ok && e.SubmittedAt == nil {
+			markSubmitted(e, prev)
+			reconciled++
+		}
+	}
+	if reconciled > 0 {
+		op.log.JustLog(fmt.Sprintf("%d script(s) were finished from the outbox", reconciled))
+		if err := SaveManifest(dir, manifest); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	submitted, missing, duplicates, rejected, pending := reconciled, 0, 0, 0, 0
+	for i := range manifest.Entries {
+		if ctx.Err() != nil {
+			break
+		}
+		e := &manifest.Entries[i]
+		if e.SubmittedAt != nil {
+			continue
+		}
+		if p, ok := op.pausedFor(e.CampaignID); ok {
+			op.log.JustLog(fmt.Sprintf("Skipping script %s: %s", e.ScriptID, pausedReason(p)))
+			continue
+		}
+		if inFlight[e.AssignmentID] {
+			op.log.JustLog(fmt.Sprintf("Script %s is still being uploaded from the outbox", e.ScriptID))
+			pending++
+			continue
+		}
+
+		recPath, err := recording.FindForLanguage(dir, e.Language, e.ScriptID, e.AssignmentID)
+		if err != nil {
+			if errors.Is(err, recording.ErrNotFound) {
+				missing++
+				op.log.JustLog("No recording for script " + e.ScriptID)
+				continue
+			}
+			return err
+		}
+
+		op.log.Log(fmt.Sprintf("Submitting %s for campaign %s...", filepath.Base(recPath), e.CampaignName), 800)
+		val, err := op.submitEntry(ctx, *e, recPath)
+		if errors.Is(err, ErrUploadInFlight) {
+			op.log.JustLog(fmt.Sprintf("Script %s: %v", e.ScriptID, err))
+			pending++
+			continue
+		}
+		if prev, ok := done[e.AssignmentID]; ok && errors.Is(err, ErrDuplicateAudio) {
+			markSubmitted(e, prev)
+			submitted++
+			if err := SaveManifest(dir, manifest); err != nil {
+				return err
+			}
+			continue
+		}
+		if errors.Is(err, ErrDuplicateAudio) {
+			op.log.JustLog(fmt.Sprintf("Skipping script %s: %v", e.ScriptID, err))
+			if err := recording.MarkDuplicate(recPath); err != nil {
+				op.log.JustLog("Failed to move duplicate recording: " + err.Error())
+			}
+			duplicates++
+			continue
+		}
+		var qe *QualityError
+		if errors.As(err, &qe) {
+			op.log.JustLog(fmt.Sprintf("Rejected %s: %v", filepath.Base(recPath), qe))
+			if err := recording.MarkRejected(recPath); err != nil {
+				op.log.JustLog("Failed to move rejected recording: " + err.Error())
+			}
+			rejected++
+			continue
+		}
+		if err != nil {
+			op.log.JustLog(fmt.Sprintf("Submit %s failed: %v", e.ScriptID, err))
+			errs = append(errs, fmt.Errorf("script %s: %w", e.ScriptID, err))
+			// A flagged upload still reached the server, so it must not be sent again.
+			if !errors.Is(err, ErrNeedsReview) {
+				continue
+			}
+		}
+
+		now := time.Now()
+		e.SubmittedAt = &now
+		e.FileID = val.VirtualID
+		submitted++
+
+		if err := SaveManifest(dir, manifest); err != nil {
+			return err
+		}
+	}
+
+	op.log.Log(fmt.Sprintf("Submitted %d recording(s), %d still uploading, %d still missing, %d duplicate(s) set aside, %d rejected",
+		submitted, pending, missing, duplicates, rejected), 1200)
+	return errors.Join(errs...)
+}
+
+// submittedAssignments maps assignment IDs to this account's intact ledger submissions.
+func (op *Operation) submittedAssignments() map[string]ledger.Entry {
+	done := map[string]ledger.Entry{}
+	for _, e := range op.ledger.Entries() {
+		if e.Type == ledger.TypeSubmission && !e.Corrupt && e.Account == op.session.Email && e.AssignmentID != "" {
+			done[e.AssignmentID] = e
+		}
+	}
+	return done
+}
+
+func markSubmitted(e *model.ManifestEntry, prev ledger.Entry) {
+	at := prev.SubmittedAt
+	e.SubmittedAt = &at
+	e.FileID = prev.FileID
+}
+
+func (op *Operation) submitEntry(ctx context.Context, e model.ManifestEntry, recPath string) (model.FileUploadValidationResponse, error) {
+	text := e.RomanizedContent
+	if text == "" {
+		text = e.Content
+	}
+	webmPath, cleanup, err := op.encodeRecording(ctx, recPath, text)
+	if err != nil {
+		return model.FileUploadValidationResponse{}, err
+	}
+	defer cleanup()
+
+	return op.UploadAudio(ctx, uploadJob{
+		Campaign:     model.Campaign{VirtualID: e.CampaignID, CampaignName: e.CampaignName},
+		ScriptID:     e.ScriptID,
+		AssignmentID: e.AssignmentID,
+		Language:     e.Language,
+		Path:         webmPath,
+	})
+}
+
+func LoadManifest(dir string) (model.ScriptManifest, error) {
+	var m model.ScriptManifest
+	b, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return m, fmt.Errorf("read manifest: %w", err)
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return m, fmt.Errorf("decode manifest: %w", err)
+	}
+	return m, nil
+}
+
+func SaveManifest(dir string, m model.ScriptManifest) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, manifestFile), b, 0o644)
+}
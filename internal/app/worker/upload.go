@@ -0,0 +1,300 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/client/tts"
+	"github.com/widiskel/poseidon-voice-bot/internal/ledger"
+	"github.com/widiskel/poseidon-voice-bot/internal/model"
+	"github.com/widiskel/poseidon-voice-bot/internal/outbox"
+	"github.com/widiskel/poseidon-voice-bot/internal/utils/exception"
+)
+
+type uploadJob struct {
+	Campaign     model.Campaign
+	ScriptID     string
+	AssignmentID string
+	Language     string
+	Path         string
+}
+
+func (op *Operation) UploadAudio(ctx context.Context, job uploadJob) (model.FileUploadValidationResponse, error) {
+	c := job.Campaign
+	startedAt := time.Now()
+	fileName := fmt.Sprintf("audio_recording_%d.webm", startedAt.UnixMilli())
+
+	sp, err := op.outbox.Spool(job.Path)
+	if err != nil {
+		return model.FileUploadValidationResponse{}, err
+	}
+	if err := op.checkDuplicate(sp.SHA256); err != nil {
+		op.outbox.Discard(sp)
+		return model.FileUploadValidationResponse{}, err
+	}
+
+	up, err := op.client.InitUpload(ctx, c.VirtualID, model.FileUploadRequest{
+		ContentType:        "audio/webm",
+		FileName:           fileName,
+		ScriptAssignmentID: job.AssignmentID,
+	})
+	if err != nil {
+		op.outbox.Discard(sp)
+		return model.FileUploadValidationResponse{}, err
+	}
+
+	if err := op.outbox.Claim(&sp, up.FileID); err != nil {
+		op.outbox.Discard(sp)
+		return model.FileUploadValidationResponse{}, err
+	}
+	it := outbox.Item{
+		FileID:       up.FileID,
+		ObjectKey:    up.ObjectKey,
+		PresignedURL: up.PresignedURL,
+		FileName:     fileName,
+		SHA256:       sp.SHA256,
+		Size:         sp.Size,
+		Path:         sp.Path,
+		Account:      op.session.Email,
+		CampaignID:   c.VirtualID,
+		CampaignName: c.CampaignName,
+		ScriptID:     job.ScriptID,
+		AssignmentID: job.AssignmentID,
+		Language:     job.Language,
+		Source:       op.session.Source,
+		State:        outbox.StateInitialized,
+		StartedAt:    startedAt,
+	}
+	if err := op.outbox.Put(it); err != nil {
+		op.outbox.Discard(sp)
+		return model.FileUploadValidationResponse{}, err
+	}
+	return op.completeUpload(ctx, &it)
+}
+
+// completeUpload drives an outbox item from whatever state it was left in to validated.
+func (op *Operation) completeUpload(ctx context.Context, it *outbox.Item) (model.FileUploadValidationResponse, error) {
+	if it.State == outbox.StateInitialized {
+		if until, ok := tts.PresignedExpiry(it.PresignedURL); ok && time.Now().Add(presignMargin).After(until) {
+			if err := op.reinitUpload(ctx, it); err != nil {
+				return model.FileUploadValidationResponse{}, op.uploadFailed(ctx, it, err)
+			}
+		}
+		dg, err := tts.PutPresignedWebM(ctx, it.PresignedURL, it.Path, op.putOptions())
+		if err != nil {
+			return model.FileUploadValidationResponse{}, op.uploadFailed(ctx, it, err)
+		}
+		if dg.HashHex != it.SHA256 || dg.FileSize != it.Size {
+			err := fmt.Errorf("audio changed since it was hashed: sent sha256=%s size=%d", dg.HashHex, dg.FileSize)
+			return model.FileUploadValidationResponse{}, op.uploadFailed(ctx, it, err)
+		}
+		it.State = outbox.StateUploaded
+		if err := op.outbox.Put(*it); err != nil {
+			return model.FileUploadValidationResponse{}, err
+		}
+	}
+
+	if it.State == outbox.StateUploaded {
+		val, err := op.client.ValidateUpload(ctx, model.FileUploadValidationRequest{
+			ContentType: "audio/webm",
+			ObjectKey:   it.ObjectKey,
+			Sha256Hash:  it.SHA256,
+			Filesize:    it.Size,
+			FileName:    it.FileName,
+			VirtualID:   it.FileID,
+			CampaignID:  it.CampaignID,
+		})
+		// The server forgets an upload once it is validated, so a 404 may mean an earlier response was lost.
+		if err != nil && errors.Is(exception.Classify(err), exception.ErrNotFound) {
+			if found, ferr := op.client.GetFile(ctx, it.FileID); ferr == nil {
+				op.log.JustLog(fmt.Sprintf("Upload %s was already validated", it.FileID))
+				val, err = found, nil
+			}
+		}
+		if err != nil {
+			return model.FileUploadValidationResponse{}, op.uploadFailed(ctx, it, err)
+		}
+		it.State = outbox.StateValidated
+		it.Result = &val
+		if err := op.outbox.Put(*it); err != nil {
+			return val, err
+		}
+	}
+
+	val := *it.Result
+	verified, corrupt := checkIntegrity(*it, val)
+	if !verified {
+		op.log.JustLog(fmt.Sprintf("Warning: server returned no hash or size for upload %s, integrity unverified", it.FileID))
+	}
+	if _, ok := op.ledger.FindFileID(it.FileID); !ok {
+		op.record(*it, val, corrupt != nil)
+	}
+	if err := op.outbox.Done(it.FileID); err != nil {
+		op.log.JustLog("Failed to clear outbox entry: " + err.Error())
+	}
+	if err := op.pauseIfFlagged(*it, val); err != nil {
+		return val, err
+	}
+	if corrupt != nil {
+		op.log.JustLog(corrupt.Error())
+		return val, exception.Wrap(exception.ClassCorruptUpload, corrupt)
+	}
+	if val.FileStatus != "UPLOADED" {
+		return val, fmt.Errorf("file status unexpected: %s", val.FileStatus)
+	}
+
+	op.log.Log(fmt.Sprintf("Upload validated. Awarded=%d verified=%v", val.PointsAwarded, val.IsVerifiedQuality), 1200)
+	return val, nil
+}
+
+// presignMargin re-initializes uploads whose presigned URL would expire mid-transfer.
+const presignMargin = 30 * time.Second
+
+func (op *Operation) reinitUpload(ctx context.Context, it *outbox.Item) error {
+	op.log.JustLog(fmt.Sprintf("Presigned URL for upload %s expired, re-initializing", it.FileID))
+	up, err := op.client.InitUpload(ctx, it.CampaignID, model.FileUploadRequest{
+		ContentType:        "audio/webm",
+		FileName:           it.FileName,
+		ScriptAssignmentID: it.AssignmentID,
+	})
+	if err != nil {
+		return fmt.Errorf("re-initialize upload: %w", err)
+	}
+	old := it.FileID
+	it.FileID = up.FileID
+	it.ObjectKey = up.ObjectKey
+	it.PresignedURL = up.PresignedURL
+	return op.outbox.Replace(old, *it)
+}
+
+func (op *Operation) putOptions() tts.PutOptions {
+	return tts.PutOptions{
+		Client:         op.uploadHTTP,
+		BytesPerSecond: int64(op.cfg.HTTP.UploadLimitKiB) * 1024,
+		IdleTimeout:    op.cfg.HTTP.UploadTimeout.Std(),
+		Progress: func(sent, total int64) {
+			pct := int64(100)
+			if total > 0 {
+				pct = sent * 100 / total
+			}
+			op.log.Progress(fmt.Sprintf("Uploading %s / %s (%d%%)", formatBytes(sent), formatBytes(total), pct))
+		},
+	}
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+func (op *Operation) uploadFailed(ctx context.Context, it *outbox.Item, cause error) error {
+	if ctx.Err() != nil {
+		return cause
+	}
+	it.Attempts++
+	it.LastError = cause.Error()
+	if it.Attempts >= op.cfg.Outbox.MaxAttempts {
+		op.log.JustLog(fmt.Sprintf("Upload %s failed %d times, moved to the dead-letter list", it.FileID, it.Attempts))
+		if err := op.outbox.Bury(*it); err != nil {
+			op.log.JustLog("Failed to update outbox: " + err.Error())
+		}
+		return cause
+	}
+	if err := op.outbox.Put(*it); err != nil {
+		op.log.JustLog("Failed to update outbox: " + err.Error())
+	}
+	return cause
+}
+
+// resumeOutbox finishes uploads left behind by a crash or a failed validation before any new work starts.
+func (op *Operation) resumeOutbox(ctx context.Context) {
+	items, err := op.outbox.Pending(op.session.Email)
+	if err != nil {
+		op.log.JustLog("Failed to read outbox: " + err.Error())
+		return
+	}
+	for i := range items {
+		if ctx.Err() != nil {
+			return
+		}
+		it := &items[i]
+		op.log.Log(fmt.Sprintf("Resuming upload %s for %s (%s, attempt %d)", it.FileID, it.CampaignName, it.State, it.Attempts+1), 800)
+
+		_, err := op.completeUpload(ctx, it)
+		if it.State == outbox.StateValidated && it.Source == model.SourceRecording {
+			op.finishRecording(it.Language, it.ScriptID, it.AssignmentID)
+		}
+		if err != nil {
+			op.log.JustLog(fmt.Sprintf("Resumed upload %s: %v", it.FileID, err))
+		}
+	}
+}
+
+type CorruptUploadError struct {
+	FileID    string
+	LocalHash string
+	LocalSize int
+	Hash      string
+	Size      int
+}
+
+func (e *CorruptUploadError) Error() string {
+	return fmt.Sprintf("corrupt upload %s: server has sha256=%s size=%d, local file has sha256=%s size=%d",
+		e.FileID, e.Hash, e.Size, e.LocalHash, e.LocalSize)
+}
+
+// checkIntegrity compares the server's hash and size with the local ones. When the server leaves either
+// out the upload cannot be verified, which is not treated as corruption.
+func checkIntegrity(it outbox.Item, val model.FileUploadValidationResponse) (bool, error) {
+	if val.FileHash == "" || val.FileSize == 0 {
+		return false, nil
+	}
+	if strings.EqualFold(val.FileHash, it.SHA256) && val.FileSize == it.Size {
+		return true, nil
+	}
+	return true, &CorruptUploadError{
+		FileID:    it.FileID,
+		LocalHash: it.SHA256,
+		LocalSize: it.Size,
+		Hash:      val.FileHash,
+		Size:      val.FileSize,
+	}
+}
+
+func (op *Operation) record(it outbox.Item, val model.FileUploadValidationResponse, corrupt bool) {
+	err := op.ledger.Append(ledger.Entry{
+		Account:          it.Account,
+		CampaignID:       it.CampaignID,
+		CampaignName:     it.CampaignName,
+		ScriptID:         it.ScriptID,
+		AssignmentID:     it.AssignmentID,
+		FileID:           it.FileID,
+		ObjectKey:        it.ObjectKey,
+		SHA256:           it.SHA256,
+		Size:             it.Size,
+		Language:         it.Language,
+		Source:           it.Source,
+		FileStatus:       val.FileStatus,
+		PointsAwarded:    val.PointsAwarded,
+		VerifiedQuality:  val.IsVerifiedQuality,
+		Rewarded:         val.IsRewarded,
+		FlaggedDuplicate: val.IsFlaggedDuplicate,
+		FlaggedBot:       val.IsFlaggedBot,
+		FlaggedSpam:      val.IsFlaggedSpam,
+		Corrupt:          corrupt,
+		StartedAt:        it.StartedAt,
+		SubmittedAt:      time.Now(),
+	})
+	if err != nil {
+		op.log.JustLog("Failed to record submission in ledger: " + err.Error())
+	}
+}
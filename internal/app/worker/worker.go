@@ -1,69 +1,109 @@
 package worker
 
 import (
-	"github.com/widiskel/poseidon-voice-bot/internal/client/apiclient"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/config"
 	"github.com/widiskel/poseidon-voice-bot/internal/model"
-	"github.com/widiskel/poseidon-voice-bot/internal/utils/exception"
 )
 
-func Run(session *model.Session) {
-	op := NewOperation(session)
+func Run(ctx context.Context, deps Deps, session *model.Session) {
+	op := NewOperation(deps, session)
+	defer op.log.Log("Stopped.", 0)
+
+	if session.Source == model.SourceTTS && !config.SyntheticAllowed(session.Endpoints) {
+		op.log.Log("Refusing to start: synthetic TTS audio may only be sent to a local or sandbox endpoint, not "+session.Endpoints.Poseidon, 0)
+		return
+	}
 
-	for {
-		if err := op.LoginIfNeeded(); err != nil {
+cycle:
+	for ctx.Err() == nil {
+		if err := op.LoginIfNeeded(ctx); err != nil {
 			op.log.JustLog("Failed to login: " + err.Error())
-			if stop := exception.HandleError(op.log, err); stop {
+			if op.handleError(ctx, err, false) == config.ActionStop {
 				return
 			}
 			continue
 		}
 
-		if err := op.GetUserInformation(); err != nil {
+		if err := op.GetUserInformation(ctx); err != nil {
 			op.log.Log("Failed to get user information: " + err.Error())
-			if apiErr, ok := err.(*apiclient.Error); ok && apiErr.IsStatus(401) {
-				op.log.Log("Session Expired Resetting JWT: " + err.Error())
-				op.ResetJWT()
-				continue
-			}
-			if stop := exception.HandleError(op.log, err); stop {
+			if op.handleError(ctx, err, false) == config.ActionStop {
 				return
 			}
 			continue
 		}
 
-		if err := op.GetCampaign(); err != nil {
+		op.resumeOutbox(ctx)
+
+		if err := op.GetCampaign(ctx); err != nil {
 			op.log.JustLog("Failed to get campaigns: " + err.Error())
-			if stop := exception.HandleError(op.log, err); stop {
+			if op.handleError(ctx, err, false) == config.ActionStop {
 				return
 			}
 			continue
 		}
 
-		for _, c := range op.CampaignList.Items {
-			allowed, err := op.CheckCampaignAccess(c)
-			if err != nil {
-				op.log.JustLog("Failed to check campaign access: " + err.Error())
-				if stop := exception.HandleError(op.log, err); stop {
-					return
-				}
-				continue
+		retries := 0
+		for i := 0; i < len(op.CampaignList.Items); i++ {
+			if ctx.Err() != nil {
+				return
 			}
-			if !allowed {
-				op.log.JustLog("No access to campaign: " + c.CampaignName)
+			c := op.CampaignList.Items[i]
+
+			err := op.runCampaign(ctx, c)
+			if err == nil {
+				retries = 0
 				continue
 			}
-
-			op.log.Log("Processing campaign: "+c.CampaignName, 800)
-
-			if err := op.ProcessCampaign(c); err != nil {
-				op.log.JustLog("Failed to get campaigns: " + err.Error())
-				if stop := exception.HandleError(op.log, err); stop {
-					return
+			switch op.handleError(ctx, err, true) {
+			case config.ActionStop:
+				return
+			case config.ActionRelogin, config.ActionPauseAccount:
+				continue cycle
+			case config.ActionRetry:
+				if retries < op.cfg.Errors.StepRetries {
+					// Finish any upload the failed attempt left behind before asking for another script.
+					op.resumeOutbox(ctx)
+					retries++
+					i--
+					continue
 				}
-				continue
+				op.log.JustLog(fmt.Sprintf("Giving up on campaign %s after %d retries", c.CampaignName, retries))
 			}
+			retries = 0
+		}
+
+		op.session.Campaign = ""
+		if err := op.log.Wait(ctx, "Account processing complete. Sleeping...", op.cfg.Loop.Interval.Std()); err != nil {
+			return
 		}
+	}
+}
 
-		op.log.Log("Account processing complete. Sleeping...", 1_000_000)
+// runCampaign selects and processes one campaign. Skips are logged here; only failures are returned.
+func (op *Operation) runCampaign(ctx context.Context, c model.Campaign) error {
+	lang, access, reason, err := op.selectCampaign(ctx, c)
+	if err != nil {
+		op.log.JustLog("Failed to check campaign access: " + err.Error())
+		return err
+	}
+	if reason != "" {
+		op.log.JustLog("Skipping campaign " + c.CampaignName + ": " + reason)
+		return nil
+	}
+
+	op.log.Log(fmt.Sprintf("Processing campaign: %s [%s] (%d remaining today)", c.CampaignName, lang, access.Remaining), 800)
+
+	if err := op.ProcessCampaignQuota(ctx, c, lang, access); err != nil {
+		if errors.Is(err, ErrNeedsReview) {
+			op.log.Log("Stopped submitting: "+err.Error(), 3000)
+			return nil
+		}
+		op.log.JustLog("Failed to process campaign: " + err.Error())
+		return err
 	}
+	return nil
 }
@@ -0,0 +1,370 @@
+package worker_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/app/worker"
+	"github.com/widiskel/poseidon-voice-bot/internal/client/tts"
+	"github.com/widiskel/poseidon-voice-bot/internal/config"
+	"github.com/widiskel/poseidon-voice-bot/internal/fakeserver"
+	"github.com/widiskel/poseidon-voice-bot/internal/ledger"
+	"github.com/widiskel/poseidon-voice-bot/internal/model"
+	"github.com/widiskel/poseidon-voice-bot/internal/outbox"
+	"github.com/widiskel/poseidon-voice-bot/internal/review"
+	"github.com/widiskel/poseidon-voice-bot/internal/utils/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.SetPacing(false)
+	os.Exit(m.Run())
+}
+
+type harness struct {
+	srv        *fakeserver.Server
+	deps       worker.Deps
+	ledger     *ledger.Ledger
+	transcoder *tts.FakeTranscoder
+	session    *model.Session
+	recording  string
+}
+
+// newHarness wires a worker to a fresh fake server, with every path in a temp dir and one recording
+// waiting for the first script the server hands out.
+func newHarness(t *testing.T, accIdx int) *harness {
+	t.Helper()
+	dir := t.TempDir()
+	srv := fakeserver.New()
+	t.Cleanup(srv.Close)
+
+	cfg := config.Default()
+	cfg.Endpoints = srv.Endpoints()
+	cfg.Paths.Ledger = filepath.Join(dir, "ledger.jsonl")
+	cfg.Paths.Review = filepath.Join(dir, "review.json")
+	cfg.Paths.Outbox = filepath.Join(dir, "outbox")
+	cfg.Audio.Source = model.SourceRecording
+	cfg.Audio.Transcoder = config.TranscoderFake
+	cfg.Loop.Interval = config.Duration(time.Hour)
+	cfg.Retry.ServerError = config.Duration(10 * time.Millisecond)
+	cfg.Retry.Transport = config.Duration(10 * time.Millisecond)
+
+	l, err := ledger.Open(cfg.Paths.Ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	recordings := filepath.Join(dir, "recordings")
+	if err := os.MkdirAll(recordings, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	rec := filepath.Join(recordings, "script-1.webm")
+	if err := os.WriteFile(rec, []byte("fake webm recording"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &tts.FakeTranscoder{}
+	return &harness{
+		srv: srv,
+		deps: worker.Deps{
+			Config:     cfg,
+			Ledger:     l,
+			Review:     review.NewStore(cfg.Paths.Review),
+			Outbox:     outbox.NewStore(cfg.Paths.Outbox),
+			Transcoder: fake,
+		},
+		ledger:     l,
+		transcoder: fake,
+		session: &model.Session{
+			AccIdx:        accIdx,
+			Email:         "fake@example.com",
+			JWT:           "test",
+			Endpoints:     srv.Endpoints(),
+			Source:        model.SourceRecording,
+			RecordingsDir: recordings,
+		},
+		recording: rec,
+	}
+}
+
+// runCycle runs the worker until done reports the cycle's work is finished, then stops it.
+func (h *harness) runCycle(t *testing.T, done func() bool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		worker.Run(ctx, h.deps, h.session)
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			cancel()
+			<-stopped
+			t.Fatalf("cycle did not finish; ledger %+v, server files %+v", h.ledger.Entries(), h.srv.Files())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	cancel()
+	<-stopped
+}
+
+func (h *harness) submissions() []ledger.Entry {
+	var out []ledger.Entry
+	for _, e := range h.ledger.Entries() {
+		if e.Type == ledger.TypeSubmission {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// submitted reports whether the recording was validated, recorded and moved out of the way.
+func (h *harness) submitted() bool {
+	if len(h.submissions()) == 0 {
+		return false
+	}
+	pending, _ := h.deps.Outbox.Pending("")
+	if len(pending) > 0 {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(filepath.Dir(h.recording), "submitted", filepath.Base(h.recording)))
+	return err == nil
+}
+
+// checkSubmission asserts the single recording ended up in the ledger, on the server and in submitted/.
+func (h *harness) checkSubmission(t *testing.T) {
+	t.Helper()
+	entries := h.submissions()
+	if len(entries) != 1 {
+		t.Fatalf("ledger has %d entries, want 1: %+v", len(entries), entries)
+	}
+	e := entries[0]
+	if e.Account != h.session.Email || e.CampaignID != "fake-campaign-en" || e.ScriptID != "script-1" ||
+		e.Source != model.SourceRecording || e.PointsAwarded != fakeserver.PointsPerUpload || e.Corrupt {
+		t.Errorf("ledger entry = %+v", e)
+	}
+
+	files := h.srv.Files()
+	if len(files) != 1 || files[0].VirtualID != e.FileID || files[0].FileHash != e.SHA256 {
+		t.Errorf("server files = %+v, want the ledger's upload %s", files, e.FileID)
+	}
+	if _, err := os.Stat(h.recording); !os.IsNotExist(err) {
+		t.Errorf("recording still in place: %v", err)
+	}
+	if pending, _ := h.deps.Outbox.Pending(""); len(pending) != 0 {
+		t.Errorf("outbox still holds %+v", pending)
+	}
+	if dead, _ := h.deps.Outbox.Dead(); len(dead) != 0 {
+		t.Errorf("outbox dead-lettered %+v", dead)
+	}
+}
+
+func TestCycle(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t, 0)
+	queuePath := filepath.Join(h.session.RecordingsDir, "to_record.json")
+	h.runCycle(t, func() bool {
+		_, err := os.Stat(queuePath)
+		return h.submitted() && err == nil
+	})
+	h.checkSubmission(t)
+
+	if h.srv.InitUploads() != 1 {
+		t.Errorf("server saw %d upload inits, want 1", h.srv.InitUploads())
+	}
+	var detected, encoded bool
+	for _, c := range h.transcoder.Calls() {
+		detected = detected || c == "detect "+h.recording
+		encoded = encoded || strings.HasPrefix(c, "webm ")
+	}
+	if !detected {
+		t.Errorf("transcoder calls %q do not detect the recording", h.transcoder.Calls())
+	}
+	if encoded {
+		t.Errorf("a WebM recording was re-encoded: %q", h.transcoder.Calls())
+	}
+
+	queue, err := os.ReadFile(queuePath)
+	if err != nil || !strings.Contains(string(queue), "script-2") {
+		t.Errorf("next script not queued for recording: %v %s", err, queue)
+	}
+}
+
+func TestCycleResumesOutboxAfterFailedValidation(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t, 1)
+	h.srv.FailValidations(1)
+
+	h.runCycle(t, h.submitted)
+	h.checkSubmission(t)
+
+	// The retry finishes the upload from the outbox instead of starting a new one.
+	if h.srv.InitUploads() != 1 {
+		t.Errorf("server saw %d upload inits, want 1", h.srv.InitUploads())
+	}
+}
+
+func TestCycleReinitializesExpiringUpload(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t, 2)
+	// Shorter than the margin the worker keeps before a presigned URL expires.
+	h.srv.PresignTTL(10 * time.Second)
+
+	h.runCycle(t, h.submitted)
+	h.checkSubmission(t)
+
+	if h.srv.InitUploads() != 2 {
+		t.Errorf("server saw %d upload inits, want 2 (the original and its re-initialization)", h.srv.InitUploads())
+	}
+}
+
+func TestCycleRetriesFailedPut(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t, 3)
+	h.srv.FailPuts(1)
+
+	h.runCycle(t, h.submitted)
+	h.checkSubmission(t)
+
+	if h.srv.InitUploads() != 1 {
+		t.Errorf("server saw %d upload inits, want 1", h.srv.InitUploads())
+	}
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t, 4)
+	h.srv.AssignAhead(3)
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	if err := worker.Export(ctx, h.deps, h.session, dir); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	first, err := worker.LoadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	perCampaign := map[string]int{}
+	for _, e := range first.Entries {
+		if seen[e.AssignmentID] {
+			t.Errorf("assignment %s exported twice", e.AssignmentID)
+		}
+		seen[e.AssignmentID] = true
+		perCampaign[e.CampaignID]++
+	}
+	if perCampaign["fake-campaign-en"] != 3 {
+		t.Errorf("exported %d scripts of fake-campaign-en, want all 3 the server hands out: %+v", perCampaign["fake-campaign-en"], first.Entries)
+	}
+
+	// The server has no script left for the campaigns, so a second export adds nothing.
+	if err := worker.Export(ctx, h.deps, h.session, dir); err != nil {
+		t.Fatalf("second Export: %v", err)
+	}
+	second, err := worker.LoadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Entries) != len(first.Entries) {
+		t.Errorf("second export grew the manifest from %d to %d entries", len(first.Entries), len(second.Entries))
+	}
+}
+
+func TestExportStopsOnRepeatedAssignment(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t, 5)
+	dir := t.TempDir()
+
+	if err := worker.Export(context.Background(), h.deps, h.session, dir); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	m, err := worker.LoadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	perCampaign := map[string]int{}
+	for _, e := range m.Entries {
+		perCampaign[e.CampaignID]++
+	}
+	if perCampaign["fake-campaign-en"] != 1 {
+		t.Errorf("exported %d scripts of fake-campaign-en, want the one pending assignment: %+v", perCampaign["fake-campaign-en"], m.Entries)
+	}
+}
+
+func TestSubmitReconcilesResumedUpload(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t, 6)
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	if err := worker.Export(ctx, h.deps, h.session, dir); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	m, err := worker.LoadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry model.ManifestEntry
+	for _, e := range m.Entries {
+		if e.CampaignID == "fake-campaign-en" {
+			entry = e
+		}
+	}
+	rec := filepath.Join(dir, entry.ScriptID+".webm")
+	if err := os.WriteFile(rec, []byte("fake webm session recording"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first submit uploads the audio but loses the validation, leaving the upload in the outbox.
+	h.srv.FailValidations(1)
+	if err := worker.Submit(ctx, h.deps, h.session, dir); err == nil {
+		t.Fatal("Submit succeeded although validation failed")
+	}
+	if pending, _ := h.deps.Outbox.Pending(""); len(pending) != 1 {
+		t.Fatalf("outbox holds %d uploads after the failed validation, want 1", len(pending))
+	}
+
+	// The second finishes it from the outbox and marks the manifest entry without uploading again.
+	if err := worker.Submit(ctx, h.deps, h.session, dir); err != nil {
+		t.Fatalf("second Submit: %v", err)
+	}
+	m, err = worker.LoadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subs := h.submissions()
+	if len(subs) != 1 {
+		t.Fatalf("ledger has %d submissions, want 1: %+v", len(subs), subs)
+	}
+	for _, e := range m.Entries {
+		if e.AssignmentID == entry.AssignmentID && (e.SubmittedAt == nil || e.FileID != subs[0].FileID) {
+			t.Errorf("manifest entry = %+v, want it submitted as %s", e, subs[0].FileID)
+		}
+	}
+	if h.srv.InitUploads() != 1 {
+		t.Errorf("server saw %d upload inits, want 1", h.srv.InitUploads())
+	}
+	if _, err := os.Stat(rec); err != nil {
+		t.Errorf("recording was moved: %v", err)
+	}
+}
+
+func TestCycleRecoversLostValidation(t *testing.T) {
+	t.Parallel()
+	h := newHarness(t, 7)
+	h.srv.DropValidations(1)
+
+	h.runCycle(t, h.submitted)
+	h.checkSubmission(t)
+
+	if h.srv.InitUploads() != 1 {
+		t.Errorf("server saw %d upload inits, want 1", h.srv.InitUploads())
+	}
+}
@@ -7,11 +7,15 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"reflect"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/widiskel/poseidon-voice-bot/internal/client/breaker"
+	"github.com/widiskel/poseidon-voice-bot/internal/client/transport"
 	"github.com/widiskel/poseidon-voice-bot/internal/model"
 	"github.com/widiskel/poseidon-voice-bot/internal/utils/logger"
 )
@@ -40,14 +44,18 @@ func (e *Error) IsServerError() bool    { return e.StatusCode >= 500 }
 
 type ApiClient struct {
 	http           *http.Client
+	retry          RetryPolicy
 	DefaultHeaders map[string]string
 	log            *logger.ClassLogger
 }
 
-func New(sess *model.Session) *ApiClient {
-	timeout := 60 * time.Second
+func New(sess *model.Session, timeout time.Duration, retry RetryPolicy) *ApiClient {
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
 	return &ApiClient{
-		http: &http.Client{Timeout: timeout},
+		http:  &http.Client{Timeout: timeout, Transport: transport.Shared()},
+		retry: retry.withDefaults(),
 		DefaultHeaders: map[string]string{
 			"Accept":          "application/json",
 			"Accept-Language": "en-US,en;q=0.9,id;q=0.8",
@@ -72,6 +80,7 @@ func (c *ApiClient) BuildHeaders(additional map[string]string) http.Header {
 /* ====================== Call ====================== */
 
 func (c *ApiClient) Call(
+	ctx context.Context,
 	URL string,
 	method string,
 	payload any,
@@ -88,7 +97,7 @@ func (c *ApiClient) Call(
 		return nil, fmt.Errorf("invalid url: %w", err)
 	}
 
-	var body io.Reader
+	var body []byte
 	var bodyPreview string
 
 	switch m {
@@ -108,7 +117,7 @@ func (c *ApiClient) Call(
 			if err != nil {
 				return nil, fmt.Errorf("json encode: %w", err)
 			}
-			body = bytes.NewReader(buf)
+			body = buf
 
 			bodyPreview = prettyJSON(payload)
 
@@ -123,13 +132,57 @@ func (c *ApiClient) Call(
 		}
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), m, u.String(), body)
+	for attempt := 1; ; attempt++ {
+		res, sent, err := c.do(ctx, m, u, body, bodyPreview, additionalHeaders)
+		if err == nil {
+			return res, nil
+		}
+		delay, ok := c.retry.retryDelay(attempt, m, err, sent)
+		if !ok || ctx.Err() != nil {
+			return res, err
+		}
+		c.log.JustLog(fmt.Sprintf("HTTP RETRY %s %s in %s (attempt %d/%d): %v", m, u.String(), delay.Round(time.Millisecond), attempt+1, c.retry.Attempts, err))
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return res, err
+		}
+	}
+}
+
+// do sends the request once. sent reports whether the request was fully written, which decides
+// whether a failed non-idempotent call may be repeated.
+func (c *ApiClient) do(
+	ctx context.Context,
+	m string,
+	u *url.URL,
+	body []byte,
+	bodyPreview string,
+	additionalHeaders map[string]string,
+) (*model.ApiResponse, bool, error) {
+	var wrote atomic.Bool
+	trace := &httptrace.ClientTrace{
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err == nil {
+				wrote.Store(true)
+			}
+		},
+	}
+
+	cb := breaker.For(u.Host)
+	if err := cb.Allow(); err != nil {
+		return nil, false, err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), m, u.String(), reader)
 	if err != nil {
-		return nil, fmt.Errorf("new request: %w", err)
+		return nil, false, fmt.Errorf("new request: %w", err)
 	}
 	req.Header = c.BuildHeaders(additionalHeaders)
 
-	c.log.JustLog(fmt.Sprintf(
+	c.log.Debug(fmt.Sprintf(
 		"HTTP REQUEST\nMethod : %s\nURL    : %s\nHeaders: %s\nQuery  : %s\nBody   : %s\n",
 		m,
 		u.String(),
@@ -143,14 +196,23 @@ func (c *ApiClient) Call(
 	dur := time.Since(start)
 	if err != nil {
 		c.log.JustLog(fmt.Sprintf("HTTP ERROR transport %s %s -> %v", m, u.String(), err))
-		return nil, fmt.Errorf("do request: %w", err)
+		if ctx.Err() == nil {
+			c.trip(cb, u.Host)
+		}
+		return nil, wrote.Load(), fmt.Errorf("do request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= 500 {
+		c.trip(cb, u.Host)
+	} else if cb.Success() {
+		c.log.Log(fmt.Sprintf("Circuit for %s closed again", u.Host), 0)
+	}
+
 	respBody, rbErr := io.ReadAll(resp.Body)
 	if rbErr != nil {
 		c.log.JustLog(fmt.Sprintf("HTTP ERROR read body %s %s: %v", m, u.String(), rbErr))
-		return &model.ApiResponse{StatusCode: resp.StatusCode, Data: nil}, fmt.Errorf("read body: %w", rbErr)
+		return &model.ApiResponse{StatusCode: resp.StatusCode, Data: nil}, true, fmt.Errorf("read body: %w", rbErr)
 	}
 
 	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
@@ -168,7 +230,7 @@ func (c *ApiClient) Call(
 
 	respPreview := tryPrettyJSON(respBody)
 
-	c.log.JustLog(fmt.Sprintf(
+	c.log.Debug(fmt.Sprintf(
 		"HTTP RESPONSE\nMethod : %s\nURL    : %s\nStatus : %d\nElapsed: %s\nHeaders: %s\nBody   : %s\n",
 		m,
 		u.String(),
@@ -187,10 +249,16 @@ func (c *ApiClient) Call(
 			Header:     resp.Header,
 			Duration:   dur,
 		}
-		return &model.ApiResponse{StatusCode: resp.StatusCode, Data: parsed}, he
+		return &model.ApiResponse{StatusCode: resp.StatusCode, Data: parsed, Body: respBody}, true, he
 	}
 
-	return &model.ApiResponse{StatusCode: resp.StatusCode, Data: parsed}, nil
+	return &model.ApiResponse{StatusCode: resp.StatusCode, Data: parsed, Body: respBody}, true, nil
+}
+
+func (c *ApiClient) trip(cb *breaker.Breaker, host string) {
+	if cb.Failure() {
+		c.log.Log(fmt.Sprintf("Circuit for %s opened, failing fast until the cooldown ends", host), 0)
+	}
 }
 
 /* ====================== Helpers ====================== */
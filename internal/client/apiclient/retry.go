@@ -0,0 +1,118 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/client/breaker"
+)
+
+// RetryPolicy controls how a single request is retried before the error reaches the caller.
+type RetryPolicy struct {
+	// Attempts is the total number of tries; 1 disables retrying.
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.Attempts < 1 {
+		p.Attempts = 1
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = 500 * time.Millisecond
+	}
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = p.BaseDelay
+	}
+	return p
+}
+
+// backoff is the delay before retry n (1-based): exponential, capped, with jitter in its upper half.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < n && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, p.MaxDelay)
+	return d/2 + rand.N(d/2+1)
+}
+
+// idempotent methods are retried on any transient failure. Others, such as POST /files/uploads and
+// POST /files, are only retried when the request never left this machine, so nothing is created twice.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// retryableStatus leaves out 502, which is often a Cloudflare block page that retrying only prolongs. The
+// worker still retries a 502 that exception.ForStatus classifies as a server error rather than a block.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// RetryAfter reads Retry-After as seconds or an HTTP date.
+func RetryAfter(h http.Header) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// retryDelay decides whether attempt n (1-based) may be retried and after how long.
+func (p RetryPolicy) retryDelay(n int, method string, err error, sent bool) (time.Duration, bool) {
+	if n >= p.Attempts || errors.Is(err, context.Canceled) || errors.Is(err, breaker.ErrOpen) {
+		return 0, false
+	}
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		if sent && !idempotent(method) {
+			return 0, false
+		}
+		return p.backoff(n), true
+	}
+
+	if !idempotent(method) || !retryableStatus(apiErr.StatusCode) {
+		return 0, false
+	}
+	if apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == http.StatusServiceUnavailable {
+		if d, ok := RetryAfter(apiErr.Header); ok {
+			// A wait longer than the cap is left to the worker's own back-off.
+			if d > p.MaxDelay {
+				return 0, false
+			}
+			return d, true
+		}
+	}
+	return p.backoff(n), true
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
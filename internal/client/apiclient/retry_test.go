@@ -0,0 +1,199 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/client/breaker"
+	"github.com/widiskel/poseidon-voice-bot/internal/model"
+)
+
+func TestBackoffJitterBounds(t *testing.T) {
+	p := RetryPolicy{Attempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	tests := []struct {
+		n    int
+		ceil time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{9, time.Second},
+	}
+	for _, tt := range tests {
+		for range 200 {
+			d := p.backoff(tt.n)
+			if d < tt.ceil/2 || d > tt.ceil {
+				t.Fatalf("backoff(%d) = %s, want within [%s, %s]", tt.n, d, tt.ceil/2, tt.ceil)
+			}
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"absent", "", 0, false},
+		{"seconds", "7", 7 * time.Second, true},
+		{"zero", "0", 0, true},
+		{"negative", "-3", 0, false},
+		{"http date", time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat), 90 * time.Second, true},
+		{"past http date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+		{"garbage", "soon", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.value != "" {
+				h.Set("Retry-After", tt.value)
+			}
+			got, ok := RetryAfter(h)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			// HTTP dates have a resolution of one second.
+			if diff := got - tt.want; diff < -2*time.Second || diff > 0 {
+				t.Fatalf("RetryAfter = %s, want about %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := RetryPolicy{Attempts: 3, BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second}.withDefaults()
+	status := func(code int, retryAfter string) error {
+		h := http.Header{}
+		if retryAfter != "" {
+			h.Set("Retry-After", retryAfter)
+		}
+		return &Error{StatusCode: code, Header: h}
+	}
+	transportErr := fmt.Errorf("do request: %w", &url.Error{Op: "Post", URL: "http://x", Err: errors.New("connection refused")})
+
+	tests := []struct {
+		name    string
+		n       int
+		method  string
+		err     error
+		sent    bool
+		want    bool
+		exactly time.Duration
+	}{
+		{"GET 503", 1, http.MethodGet, status(503, ""), true, true, 0},
+		{"GET 500", 1, http.MethodGet, status(500, ""), true, true, 0},
+		{"GET 504", 1, http.MethodGet, status(504, ""), true, true, 0},
+		{"GET 429", 1, http.MethodGet, status(429, ""), true, true, 0},
+		{"GET 502 is not retried", 1, http.MethodGet, status(502, ""), true, false, 0},
+		{"GET 404 is not retried", 1, http.MethodGet, status(404, ""), true, false, 0},
+		{"last attempt", 3, http.MethodGet, status(503, ""), true, false, 0},
+		{"GET transport error", 1, http.MethodGet, transportErr, true, true, 0},
+		{"POST 503 after writing", 1, http.MethodPost, status(503, ""), true, false, 0},
+		{"POST transport error after writing", 1, http.MethodPost, transportErr, true, false, 0},
+		{"POST transport error before writing", 1, http.MethodPost, transportErr, false, true, 0},
+		{"Retry-After seconds", 1, http.MethodGet, status(429, "1"), true, true, time.Second},
+		{"Retry-After over the cap", 1, http.MethodGet, status(503, "5"), true, false, 0},
+		{"cancelled", 1, http.MethodGet, context.Canceled, false, false, 0},
+		{"circuit open", 1, http.MethodGet, &breaker.OpenError{Host: "x", Until: time.Now().Add(time.Minute)}, false, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := p.retryDelay(tt.n, tt.method, tt.err, tt.sent)
+			if ok != tt.want {
+				t.Fatalf("retry = %v, want %v", ok, tt.want)
+			}
+			if ok && tt.exactly > 0 && d != tt.exactly {
+				t.Fatalf("delay = %s, want %s", d, tt.exactly)
+			}
+			if ok && tt.exactly == 0 && (d <= 0 || d > p.MaxDelay) {
+				t.Fatalf("delay = %s, want a back-off within (0, %s]", d, p.MaxDelay)
+			}
+		})
+	}
+}
+
+// countingServer answers each request with the next entry of codes, repeating the last one.
+func countingServer(t *testing.T, codes []int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(hits.Add(1))
+		code := codes[min(n, len(codes))-1]
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		fmt.Fprintf(w, `{"message":"attempt %d"}`, n)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func TestCallRetries(t *testing.T) {
+	breaker.Configure(0, time.Second)
+	t.Cleanup(func() { breaker.Configure(5, 30*time.Second) })
+
+	tests := []struct {
+		name       string
+		method     string
+		codes      []int
+		retryAfter string
+		wantHits   int32
+		wantErr    bool
+	}{
+		{"GET recovers", http.MethodGet, []int{503, 500, 200}, "", 3, false},
+		{"GET gives up after all attempts", http.MethodGet, []int{503}, "", 4, true},
+		{"GET 502 fails at once", http.MethodGet, []int{502, 200}, "", 1, true},
+		{"GET honours a short Retry-After", http.MethodGet, []int{429, 200}, "0", 2, false},
+		{"GET leaves a long Retry-After to the worker", http.MethodGet, []int{503, 200}, "60", 1, true},
+		{"POST is not repeated once sent", http.MethodPost, []int{503, 200}, "", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			if tt.retryAfter != "" {
+				h.Set("Retry-After", tt.retryAfter)
+			}
+			srv, hits := countingServer(t, tt.codes, h)
+			c := New(&model.Session{}, 5*time.Second, RetryPolicy{Attempts: 4, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+
+			_, err := c.Call(context.Background(), srv.URL+"/x", tt.method, map[string]any{"a": 1}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := hits.Load(); got != tt.wantHits {
+				t.Fatalf("server saw %d attempts, want %d", got, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestCallFailsFastWhileCircuitOpen(t *testing.T) {
+	breaker.Configure(1, time.Minute)
+	t.Cleanup(func() { breaker.Configure(5, 30*time.Second) })
+
+	srv, hits := countingServer(t, []int{200}, nil)
+	u, _ := url.Parse(srv.URL)
+	breaker.For(u.Host).Failure()
+
+	c := New(&model.Session{}, 5*time.Second, RetryPolicy{Attempts: 4, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+	_, err := c.Call(context.Background(), srv.URL+"/x", http.MethodGet, nil, nil)
+	if !errors.Is(err, breaker.ErrOpen) {
+		t.Fatalf("err = %v, want ErrOpen", err)
+	}
+	if got := hits.Load(); got != 0 {
+		t.Fatalf("server saw %d attempts while the circuit was open, want 0", got)
+	}
+}
@@ -0,0 +1,176 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+var ErrOpen = errors.New("circuit open")
+
+// OpenError is returned instead of sending a request while the host's circuit is open.
+type OpenError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("circuit open for %s until %s", e.Host, e.Until.Local().Format(time.TimeOnly))
+}
+
+func (e *OpenError) Is(target error) bool { return target == ErrOpen }
+
+// Breaker tracks consecutive server and transport failures of one host. It is shared by every
+// ApiClient, so an outage trips it once instead of once per account.
+type Breaker struct {
+	host string
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  time.Time
+}
+
+// now is the breaker's clock, replaced in tests.
+var now = time.Now
+
+var (
+	mu        sync.Mutex
+	threshold = 5
+	cooldown  = 30 * time.Second
+	breakers  = map[string]*Breaker{}
+)
+
+// Configure sets how many consecutive failures open a circuit and how long it stays open before a probe.
+// A threshold below 1 disables the breaker.
+func Configure(failures int, wait time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	threshold, cooldown = failures, wait
+}
+
+func settings() (int, time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	return threshold, cooldown
+}
+
+func For(host string) *Breaker {
+	mu.Lock()
+	defer mu.Unlock()
+	b, ok := breakers[host]
+	if !ok {
+		b = &Breaker{host: host}
+		breakers[host] = b
+	}
+	return b
+}
+
+// Allow reports whether a request may go out. Once the cooldown has passed a single probe is let
+// through half-open; everyone else keeps failing fast until it comes back.
+func (b *Breaker) Allow() error {
+	limit, wait := settings()
+	if limit < 1 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := now()
+
+	switch b.state {
+	case Open:
+		if until := b.openedAt.Add(wait); t.Before(until) {
+			return &OpenError{Host: b.host, Until: until}
+		}
+		b.state = HalfOpen
+		b.probing = t
+		return nil
+	case HalfOpen:
+		// A probe that never reported back, e.g. because its context was cancelled, is replaced.
+		if until := b.probing.Add(wait); t.Before(until) {
+			return &OpenError{Host: b.host, Until: until}
+		}
+		b.probing = t
+		return nil
+	}
+	return nil
+}
+
+// Success closes the circuit and reports whether it was not closed before.
+func (b *Breaker) Success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	recovered := b.state != Closed
+	b.state = Closed
+	b.failures = 0
+	return recovered
+}
+
+// Failure counts a server or transport failure and reports whether it opened the circuit.
+func (b *Breaker) Failure() bool {
+	limit, _ := settings()
+	if limit < 1 {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == HalfOpen || (b.state == Closed && b.failures >= limit) {
+		b.state = Open
+		b.openedAt = now()
+		return true
+	}
+	return false
+}
+
+type Status struct {
+	Host  string
+	State State
+	Until time.Time
+}
+
+// Tripped lists the hosts whose circuit is not closed, sorted by host.
+func Tripped() []Status {
+	_, wait := settings()
+
+	mu.Lock()
+	list := make([]*Breaker, 0, len(breakers))
+	for _, b := range breakers {
+		list = append(list, b)
+	}
+	mu.Unlock()
+
+	var out []Status
+	for _, b := range list {
+		b.mu.Lock()
+		if b.state != Closed {
+			out = append(out, Status{Host: b.host, State: b.state, Until: b.openedAt.Add(wait)})
+		}
+		b.mu.Unlock()
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Host < out[j].Host })
+	return out
+}
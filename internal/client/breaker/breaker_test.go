@@ -0,0 +1,189 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type clock struct{ t time.Time }
+
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// setup installs a fake clock and settings for one test and restores the package state afterwards.
+func setup(t *testing.T, failures int, wait time.Duration) *clock {
+	t.Helper()
+	c := &clock{t: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	prevNow, prevThreshold, prevCooldown := now, threshold, cooldown
+	now = func() time.Time { return c.t }
+	Configure(failures, wait)
+	t.Cleanup(func() {
+		now = prevNow
+		Configure(prevThreshold, prevCooldown)
+		mu.Lock()
+		breakers = map[string]*Breaker{}
+		mu.Unlock()
+	})
+	return c
+}
+
+func state(b *Breaker) State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func TestOpensAtThreshold(t *testing.T) {
+	setup(t, 3, 30*time.Second)
+	b := For("api.example")
+
+	for i := 1; i < 3; i++ {
+		if b.Failure() {
+			t.Fatalf("failure %d opened the circuit before the threshold", i)
+		}
+		if err := b.Allow(); err != nil {
+			t.Fatalf("Allow after %d failures: %v", i, err)
+		}
+	}
+	if !b.Failure() {
+		t.Fatal("third failure did not open the circuit")
+	}
+	if got := state(b); got != Open {
+		t.Fatalf("state = %s, want open", got)
+	}
+
+	err := b.Allow()
+	if !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow while open = %v, want ErrOpen", err)
+	}
+	var oe *OpenError
+	if !errors.As(err, &oe) || oe.Host != "api.example" {
+		t.Fatalf("Allow while open = %#v, want an OpenError for api.example", err)
+	}
+}
+
+func TestSuccessResetsCount(t *testing.T) {
+	setup(t, 3, 30*time.Second)
+	b := For("api.example")
+
+	b.Failure()
+	b.Failure()
+	if b.Success() {
+		t.Fatal("Success on a closed circuit reported a recovery")
+	}
+	b.Failure()
+	b.Failure()
+	if got := state(b); got != Closed {
+		t.Fatalf("state = %s after non-consecutive failures, want closed", got)
+	}
+}
+
+func TestHalfOpenAfterCooldown(t *testing.T) {
+	c := setup(t, 1, 30*time.Second)
+	b := For("api.example")
+	b.Failure()
+
+	c.advance(29 * time.Second)
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow before the cooldown = %v, want ErrOpen", err)
+	}
+
+	c.advance(time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow after the cooldown = %v, want a probe", err)
+	}
+	if got := state(b); got != HalfOpen {
+		t.Fatalf("state = %s, want half-open", got)
+	}
+}
+
+func TestSingleProbe(t *testing.T) {
+	c := setup(t, 1, 30*time.Second)
+	b := For("api.example")
+	b.Failure()
+	c.advance(30 * time.Second)
+
+	if err := b.Allow(); err != nil {
+		t.Fatalf("first probe: %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("second request while probing = %v, want ErrOpen", err)
+	}
+
+	// A probe that never reports back is replaced once another cooldown has passed.
+	c.advance(30 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe after a stale one: %v", err)
+	}
+}
+
+func TestProbeOutcome(t *testing.T) {
+	tests := []struct {
+		name    string
+		success bool
+		want    State
+	}{
+		{"failure re-opens", false, Open},
+		{"success closes", true, Closed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := setup(t, 2, 30*time.Second)
+			b := For("api.example")
+			b.Failure()
+			b.Failure()
+			c.advance(30 * time.Second)
+			if err := b.Allow(); err != nil {
+				t.Fatalf("probe: %v", err)
+			}
+
+			if tt.success {
+				if !b.Success() {
+					t.Fatal("successful probe did not report a recovery")
+				}
+			} else if !b.Failure() {
+				t.Fatal("failed probe did not report re-opening")
+			}
+			if got := state(b); got != tt.want {
+				t.Fatalf("state = %s, want %s", got, tt.want)
+			}
+
+			err := b.Allow()
+			if tt.want == Closed && err != nil {
+				t.Fatalf("Allow after recovery = %v", err)
+			}
+			if tt.want == Open && !errors.Is(err, ErrOpen) {
+				t.Fatalf("Allow after a failed probe = %v, want ErrOpen for a fresh cooldown", err)
+			}
+		})
+	}
+}
+
+func TestSharedPerHost(t *testing.T) {
+	setup(t, 1, 30*time.Second)
+	if For("a.example") != For("a.example") {
+		t.Fatal("For returned different breakers for the same host")
+	}
+	For("a.example").Failure()
+	if err := For("b.example").Allow(); err != nil {
+		t.Fatalf("an open circuit on one host blocked another: %v", err)
+	}
+
+	tripped := Tripped()
+	if len(tripped) != 1 || tripped[0].Host != "a.example" || tripped[0].State != Open {
+		t.Fatalf("Tripped() = %+v, want a.example open", tripped)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	setup(t, 0, 30*time.Second)
+	b := For("api.example")
+	for range 10 {
+		if b.Failure() {
+			t.Fatal("a disabled breaker opened")
+		}
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("a disabled breaker blocked a request: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package poseidon
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/client/apiclient"
+	"github.com/widiskel/poseidon-voice-bot/internal/utils/exception"
+)
+
+// Error names the call that failed. It is classified, like any other error, with exception.Classify,
+// which finds the wrapped apiclient.Error; test for a class with
+// errors.Is(exception.Classify(err), exception.ErrNotFound).
+type Error struct {
+	Op         string
+	StatusCode int
+	Message    string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("poseidon %s: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("poseidon %s: %d %s", e.Op, e.StatusCode, e.Message)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+type DecodeError struct {
+	Op  string
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("poseidon %s: decode response: %v", e.Op, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+func wrapError(op string, err error) error {
+	var apiErr *apiclient.Error
+	if errors.As(err, &apiErr) {
+		return &Error{
+			Op:         op,
+			StatusCode: apiErr.StatusCode,
+			Message:    exception.BodyMessage(apiErr.Body),
+			Err:        apiErr,
+		}
+	}
+	return &Error{Op: op, Err: err}
+}
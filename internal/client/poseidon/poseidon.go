@@ -0,0 +1,100 @@
+package poseidon
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/client/apiclient"
+	"github.com/widiskel/poseidon-voice-bot/internal/model"
+	"github.com/widiskel/poseidon-voice-bot/internal/utils/fingerprint"
+)
+
+const (
+	origin  = "https://app.psdn.ai"
+	referer = "https://app.psdn.ai/"
+)
+
+type Client struct {
+	api     *apiclient.ApiClient
+	session *model.Session
+}
+
+func New(api *apiclient.ApiClient, session *model.Session) *Client {
+	return &Client{api: api, session: session}
+}
+
+func (c *Client) Me(ctx context.Context) (model.UserInfo, error) {
+	var out model.UserInfo
+	err := c.do(ctx, "me", "GET", "/users/me", nil, &out)
+	return out, err
+}
+
+func (c *Client) ListCampaigns(ctx context.Context, page, size int) (model.Paginate[model.Campaign], error) {
+	q := url.Values{}
+	q.Set("page", strconv.Itoa(page))
+	q.Set("size", strconv.Itoa(size))
+
+	var out model.Paginate[model.Campaign]
+	err := c.do(ctx, "list campaigns", "GET", "/campaigns?"+q.Encode(), nil, &out)
+	return out, err
+}
+
+func (c *Client) CampaignAccess(ctx context.Context, campaignID string) (model.Access, error) {
+	var out model.Access
+	err := c.do(ctx, "campaign access", "GET", "/campaigns/"+url.PathEscape(campaignID)+"/access", nil, &out)
+	return out, err
+}
+
+func (c *Client) NextScript(ctx context.Context, lang, campaignID string) (model.CampaignScript, error) {
+	q := url.Values{}
+	q.Set("language_code", lang)
+	q.Set("campaign_id", campaignID)
+
+	var out model.CampaignScript
+	err := c.do(ctx, "next script", "GET", "/scripts/next?"+q.Encode(), nil, &out)
+	return out, err
+}
+
+func (c *Client) InitUpload(ctx context.Context, campaignID string, req model.FileUploadRequest) (model.FileUploadResponse, error) {
+	var out model.FileUploadResponse
+	err := c.do(ctx, "init upload", "POST", "/files/uploads/"+url.PathEscape(campaignID), req, &out)
+	return out, err
+}
+
+func (c *Client) ValidateUpload(ctx context.Context, req model.FileUploadValidationRequest) (model.FileUploadValidationResponse, error) {
+	var out model.FileUploadValidationResponse
+	err := c.do(ctx, "validate upload", "POST", "/files", req, &out)
+	return out, err
+}
+
+// GetFile fetches a validated file by its virtual ID.
+func (c *Client) GetFile(ctx context.Context, fileID string) (model.FileUploadValidationResponse, error) {
+	var out model.FileUploadValidationResponse
+	err := c.do(ctx, "get file", "GET", "/files/"+url.PathEscape(fileID), nil, &out)
+	return out, err
+}
+
+func (c *Client) do(ctx context.Context, op, method, path string, payload, out any) error {
+	base := c.session.Endpoints.WithDefaults().Poseidon
+	resp, err := c.api.Call(ctx, base+path, method, payload, c.headers())
+	if err != nil {
+		return wrapError(op, err)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := resp.Decode(out); err != nil {
+		return &DecodeError{Op: op, Err: err}
+	}
+	return nil
+}
+
+func (c *Client) headers() map[string]string {
+	return map[string]string{
+		"Authorization":            "Bearer " + c.session.JWT,
+		"Origin":                   origin,
+		"Referer":                  referer,
+		"X-Fingerprint-Request-Id": fingerprint.MakeRequestID(),
+	}
+}
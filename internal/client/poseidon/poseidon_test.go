@@ -0,0 +1,245 @@
+package poseidon_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/client/apiclient"
+	"github.com/widiskel/poseidon-voice-bot/internal/client/poseidon"
+	"github.com/widiskel/poseidon-voice-bot/internal/fakeserver"
+	"github.com/widiskel/poseidon-voice-bot/internal/model"
+	"github.com/widiskel/poseidon-voice-bot/internal/utils/exception"
+)
+
+const campaignEN = "fake-campaign-en"
+
+func newClient(t *testing.T, jwt string) (*poseidon.Client, *fakeserver.Server) {
+	t.Helper()
+	srv := fakeserver.New()
+	t.Cleanup(srv.Close)
+	sess := &model.Session{JWT: jwt, Endpoints: srv.Endpoints()}
+	api := apiclient.New(sess, 5*time.Second, apiclient.RetryPolicy{Attempts: 1})
+	return poseidon.New(api, sess), srv
+}
+
+// upload runs init, PUT and validation for the next English script and returns the validation result.
+func upload(t *testing.T, ctx context.Context, c *poseidon.Client, audio []byte) (model.FileUploadResponse, model.FileUploadValidationResponse, error) {
+	t.Helper()
+	sc, err := c.NextScript(ctx, "en", campaignEN)
+	if err != nil {
+		t.Fatalf("NextScript: %v", err)
+	}
+	up, err := c.InitUpload(ctx, campaignEN, model.FileUploadRequest{
+		ContentType:        "audio/webm",
+		FileName:           "a.webm",
+		ScriptAssignmentID: sc.AssignmentID,
+	})
+	if err != nil {
+		t.Fatalf("InitUpload: %v", err)
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPut, up.PresignedURL, bytes.NewReader(audio))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT: %v", err)
+	}
+	resp.Body.Close()
+
+	sum := sha256.Sum256(audio)
+	val, err := c.ValidateUpload(ctx, model.FileUploadValidationRequest{
+		ContentType: "audio/webm",
+		ObjectKey:   up.ObjectKey,
+		Sha256Hash:  hex.EncodeToString(sum[:]),
+		Filesize:    len(audio),
+		FileName:    "a.webm",
+		VirtualID:   up.FileID,
+		CampaignID:  campaignEN,
+	})
+	return up, val, err
+}
+
+func TestDecoding(t *testing.T) {
+	ctx := context.Background()
+	c, srv := newClient(t, "jwt")
+
+	me, err := c.Me(ctx)
+	if err != nil {
+		t.Fatalf("Me: %v", err)
+	}
+	if me.ID != "fake-user" || me.Email != "fake@example.com" || me.PrimaryLanguage != "en" {
+		t.Errorf("Me = %+v", me)
+	}
+
+	page, err := c.ListCampaigns(ctx, 1, 3)
+	if err != nil {
+		t.Fatalf("ListCampaigns: %v", err)
+	}
+	if len(page.Items) != 3 || page.Total != 4 || page.Pages != 2 || page.Items[0].VirtualID != campaignEN {
+		t.Errorf("ListCampaigns = %+v", page)
+	}
+	if !page.Items[0].IsScripted || page.Items[0].SupportedLanguages[0] != "en" {
+		t.Errorf("campaign fields not decoded: %+v", page.Items[0])
+	}
+
+	access, err := c.CampaignAccess(ctx, campaignEN)
+	if err != nil {
+		t.Fatalf("CampaignAccess: %v", err)
+	}
+	if !access.Allowed || access.Cap != fakeserver.DailyCap || access.Remaining != fakeserver.DailyCap {
+		t.Errorf("CampaignAccess = %+v", access)
+	}
+
+	sc, err := c.NextScript(ctx, "en", campaignEN)
+	if err != nil {
+		t.Fatalf("NextScript: %v", err)
+	}
+	if sc.AssignmentID == "" || sc.Script.ID == "" || sc.Script.Language.Code != "en" || sc.Script.Content == "" {
+		t.Errorf("NextScript = %+v", sc)
+	}
+
+	audio := []byte("fake webm audio")
+	up, val, err := upload(t, ctx, c, audio)
+	if err != nil {
+		t.Fatalf("ValidateUpload: %v", err)
+	}
+	if up.FileID == "" || up.ObjectKey == "" || up.PresignedURL == "" {
+		t.Errorf("InitUpload = %+v", up)
+	}
+	if val.FileStatus != "UPLOADED" || val.FileSize != len(audio) || val.PointsAwarded != fakeserver.PointsPerUpload ||
+		!val.IsVerifiedQuality || val.VirtualID != up.FileID {
+		t.Errorf("ValidateUpload = %+v", val)
+	}
+	if files := srv.Files(); len(files) != 1 || files[0].FileHash != val.FileHash {
+		t.Errorf("server recorded %+v", files)
+	}
+
+	file, err := c.GetFile(ctx, up.FileID)
+	if err != nil {
+		t.Fatalf("GetFile: %v", err)
+	}
+	if file.VirtualID != up.FileID || file.FileHash != val.FileHash || file.FileSize != val.FileSize {
+		t.Errorf("GetFile = %+v, want the validated file %+v", file, val)
+	}
+}
+
+func TestStatusMapping(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		jwt    string
+		call   func(t *testing.T, c *poseidon.Client, srv *fakeserver.Server) error
+		status int
+		class  error
+	}{
+		{
+			name:   "missing JWT",
+			call:   func(_ *testing.T, c *poseidon.Client, _ *fakeserver.Server) error { _, err := c.Me(ctx); return err },
+			status: http.StatusUnauthorized,
+			class:  exception.ErrAuthExpired,
+		},
+		{
+			name: "unknown campaign",
+			jwt:  "jwt",
+			call: func(_ *testing.T, c *poseidon.Client, _ *fakeserver.Server) error {
+				_, err := c.CampaignAccess(ctx, "nope")
+				return err
+			},
+			status: http.StatusNotFound,
+			class:  exception.ErrNotFound,
+		},
+		{
+			name: "unsupported language",
+			jwt:  "jwt",
+			call: func(_ *testing.T, c *poseidon.Client, _ *fakeserver.Server) error {
+				_, err := c.NextScript(ctx, "fr", campaignEN)
+				return err
+			},
+			status: http.StatusBadRequest,
+			class:  exception.ErrClientError,
+		},
+		{
+			name: "unknown assignment",
+			jwt:  "jwt",
+			call: func(_ *testing.T, c *poseidon.Client, _ *fakeserver.Server) error {
+				_, err := c.InitUpload(ctx, campaignEN, model.FileUploadRequest{ScriptAssignmentID: "nope"})
+				return err
+			},
+			status: http.StatusNotFound,
+			class:  exception.ErrNotFound,
+		},
+		{
+			name: "server unavailable",
+			jwt:  "jwt",
+			call: func(_ *testing.T, c *poseidon.Client, srv *fakeserver.Server) error {
+				srv.FailGets(1)
+				_, err := c.ListCampaigns(ctx, 1, 50)
+				return err
+			},
+			status: http.StatusServiceUnavailable,
+			class:  exception.ErrServer,
+		},
+		{
+			name: "validation fails",
+			jwt:  "jwt",
+			call: func(t *testing.T, c *poseidon.Client, srv *fakeserver.Server) error {
+				srv.FailValidations(1)
+				_, _, err := upload(t, ctx, c, []byte("audio"))
+				return err
+			},
+			status: http.StatusServiceUnavailable,
+			class:  exception.ErrServer,
+		},
+		{
+			name: "unknown file",
+			jwt:  "jwt",
+			call: func(_ *testing.T, c *poseidon.Client, _ *fakeserver.Server) error {
+				_, err := c.GetFile(ctx, "nope")
+				return err
+			},
+			status: http.StatusNotFound,
+			class:  exception.ErrNotFound,
+		},
+		{
+			name: "unknown upload",
+			jwt:  "jwt",
+			call: func(_ *testing.T, c *poseidon.Client, _ *fakeserver.Server) error {
+				_, err := c.ValidateUpload(ctx, model.FileUploadValidationRequest{VirtualID: "nope"})
+				return err
+			},
+			status: http.StatusNotFound,
+			class:  exception.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, srv := newClient(t, tt.jwt)
+			err := tt.call(t, c, srv)
+
+			var pe *poseidon.Error
+			if !errors.As(err, &pe) {
+				t.Fatalf("err = %v, want a *poseidon.Error", err)
+			}
+			if pe.StatusCode != tt.status || pe.Message == "" {
+				t.Errorf("poseidon.Error = %+v, want status %d with a message", pe, tt.status)
+			}
+			if !errors.Is(exception.Classify(err), tt.class) {
+				t.Errorf("class = %s, want %v", exception.Classify(err).Class, tt.class)
+			}
+		})
+	}
+}
+
+func TestRetryAfterSurvivesWrapping(t *testing.T) {
+	c, srv := newClient(t, "jwt")
+	srv.FailGets(1)
+
+	_, err := c.Me(context.Background())
+	if ce := exception.Classify(err); ce.RetryAfter != time.Second {
+		t.Fatalf("RetryAfter = %s, want 1s", ce.RetryAfter)
+	}
+}
@@ -0,0 +1,182 @@
+package tts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AnalyzeOptions struct {
+	FFmpeg  string
+	FFprobe string
+	// SilenceDB is the level below which audio counts as silence, e.g. -50.
+	SilenceDB float64
+}
+
+type AudioStats struct {
+	Duration        time.Duration
+	SampleRate      int
+	Channels        int
+	RMSdB           float64
+	PeakdB          float64
+	ClippingRatio   float64
+	LeadingSilence  time.Duration
+	TrailingSilence time.Duration
+}
+
+// Analyze probes the stream format with ffprobe and measures levels and silence with one ffmpeg pass.
+func Analyze(ctx context.Context, path string, opts AnalyzeOptions) (AudioStats, error) {
+	if opts.FFmpeg == "" {
+		opts.FFmpeg = "ffmpeg"
+	}
+	if opts.FFprobe == "" {
+		opts.FFprobe = "ffprobe"
+	}
+
+	st, err := probe(ctx, opts.FFprobe, path)
+	if err != nil {
+		return st, err
+	}
+
+	filter := fmt.Sprintf("astats=metadata=0:reset=0,silencedetect=noise=%gdB:d=0.2", opts.SilenceDB)
+	cmd := exec.CommandContext(ctx, opts.FFmpeg, "-hide_banner", "-nostats", "-i", path, "-af", filter, "-f", "null", "-")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return st, ctx.Err()
+		}
+		return st, fmt.Errorf("ffmpeg analyze: %v, out=%s", err, string(out))
+	}
+	parseLevels(out, &st)
+	parseSilence(out, &st)
+	return st, nil
+}
+
+func probe(ctx context.Context, ffprobe, path string) (AudioStats, error) {
+	cmd := exec.CommandContext(ctx, ffprobe, "-v", "error", "-select_streams", "a:0",
+		"-show_entries", "stream=sample_rate,channels:format=duration", "-of", "json", path)
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return AudioStats{}, ctx.Err()
+		}
+		return AudioStats{}, fmt.Errorf("ffprobe: %w", err)
+	}
+
+	var res struct {
+		Streams []struct {
+			SampleRate string `json:"sample_rate"`
+			Channels   int    `json:"channels"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &res); err != nil {
+		return AudioStats{}, fmt.Errorf("decode ffprobe output: %w", err)
+	}
+	if len(res.Streams) == 0 {
+		return AudioStats{}, fmt.Errorf("ffprobe: %s has no audio stream", path)
+	}
+
+	var st AudioStats
+	st.SampleRate, _ = strconv.Atoi(res.Streams[0].SampleRate)
+	st.Channels = res.Streams[0].Channels
+	if secs, err := strconv.ParseFloat(res.Format.Duration, 64); err == nil {
+		st.Duration = seconds(secs)
+	}
+	return st, nil
+}
+
+// parseLevels reads the "Overall" block astats prints when the stream ends.
+func parseLevels(out []byte, st *AudioStats) {
+	var overall bool
+	var peakCount, samples float64
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.Contains(line, "Parsed_astats") {
+			continue
+		}
+		if strings.HasSuffix(strings.TrimSpace(line), "Overall") {
+			overall = true
+			continue
+		}
+		if !overall {
+			continue
+		}
+		key, val, ok := strings.Cut(line[strings.Index(line, "]")+1:], ":")
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "RMS level dB":
+			st.RMSdB = v
+		case "Peak level dB":
+			st.PeakdB = v
+		case "Peak count":
+			peakCount = v
+		case "Number of samples":
+			samples = v
+		}
+	}
+	// Peak count is how often the peak level was hit; it only means clipping when that peak is full scale.
+	if st.PeakdB > -0.1 && samples > 0 {
+		st.ClippingRatio = peakCount / samples
+	}
+}
+
+func parseSilence(out []byte, st *AudioStats) {
+	type span struct{ start, end float64 }
+	var spans []span
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.Index(line, "silence_start:"); i >= 0 {
+			v, err := strconv.ParseFloat(strings.TrimSpace(line[i+len("silence_start:"):]), 64)
+			if err == nil {
+				spans = append(spans, span{start: v, end: -1})
+			}
+		}
+		if i := strings.Index(line, "silence_end:"); i >= 0 && len(spans) > 0 {
+			f := strings.Fields(line[i+len("silence_end:"):])
+			if len(f) > 0 {
+				if v, err := strconv.ParseFloat(f[0], 64); err == nil {
+					spans[len(spans)-1].end = v
+				}
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return
+	}
+
+	total := st.Duration.Seconds()
+	first, last := spans[0], spans[len(spans)-1]
+	if first.start <= 0.05 {
+		end := first.end
+		if end < 0 {
+			end = total
+		}
+		st.LeadingSilence = seconds(end)
+	}
+	if last.end < 0 || last.end >= total-0.05 {
+		if last.start > 0.05 || len(spans) > 1 {
+			st.TrailingSilence = seconds(total - last.start)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
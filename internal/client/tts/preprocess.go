@@ -0,0 +1,59 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// PreprocessOptions selects the clean-up stages run on a recording before it is encoded.
+type PreprocessOptions struct {
+	HighPassHz  int
+	TrimSilence bool
+	SilenceDB   float64
+	Loudnorm    bool
+	TargetLUFS  float64
+	SampleRate  int
+}
+
+// Filters returns the ffmpeg audio filter chain for the enabled stages, in the order they run.
+func (p PreprocessOptions) Filters() []string {
+	var chain []string
+	if p.HighPassHz > 0 {
+		chain = append(chain, fmt.Sprintf("highpass=f=%d", p.HighPassHz))
+	}
+	if p.TrimSilence {
+		// silenceremove only trims the start, so the audio is reversed to trim the end the same way.
+		trim := fmt.Sprintf("silenceremove=start_periods=1:start_duration=0.1:start_threshold=%gdB", p.SilenceDB)
+		chain = append(chain, trim, "areverse", trim, "areverse")
+	}
+	if p.Loudnorm {
+		chain = append(chain, fmt.Sprintf("loudnorm=I=%g:TP=-1.5:LRA=11", p.TargetLUFS))
+	}
+	// loudnorm works at 192 kHz, so resampling comes last.
+	if p.SampleRate > 0 {
+		chain = append(chain, fmt.Sprintf("aresample=%d", p.SampleRate))
+	}
+	return chain
+}
+
+// Preprocess runs the enabled stages on src and writes the result to dst as WAV.
+// It returns false without touching dst when no stage is enabled.
+func Preprocess(ctx context.Context, t Transcoder, src, dst string, opts PreprocessOptions) (bool, error) {
+	chain := opts.Filters()
+	if len(chain) == 0 {
+		return false, nil
+	}
+	if t == nil {
+		t = FFmpeg{}
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return false, err
+	}
+	if err := t.Filter(ctx, src, dst, chain); err != nil {
+		os.Remove(dst)
+		return false, fmt.Errorf("preprocess %s: %w", filepath.Base(src), err)
+	}
+	return true, nil
+}
@@ -0,0 +1,252 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatWAV  Format = "wav"
+	FormatFLAC Format = "flac"
+	FormatM4A  Format = "m4a"
+	FormatOGG  Format = "ogg"
+	FormatMP3  Format = "mp3"
+	FormatWebM Format = "webm"
+	// FormatMatroska is WebM or Matroska without Opus audio, which still needs re-encoding.
+	FormatMatroska Format = "matroska"
+)
+
+type EncodeOptions struct {
+	Bitrate string
+	// SampleRate resamples the output when set, e.g. 48000.
+	SampleRate int
+	Mono       bool
+}
+
+func (o EncodeOptions) validate() error {
+	if !validBitrate(o.Bitrate) {
+		return fmt.Errorf("invalid bitrate: %s (use like 48k, 64k, 96k)", o.Bitrate)
+	}
+	if o.SampleRate < 0 {
+		return fmt.Errorf("invalid sample rate: %d", o.SampleRate)
+	}
+	return nil
+}
+
+// Transcoder turns source audio into WebM/Opus and inspects audio files.
+type Transcoder interface {
+	Detect(ctx context.Context, path string) (Format, error)
+	ToWebM(ctx context.Context, src, dst string, opts EncodeOptions) error
+	// Filter applies an ffmpeg audio filter chain and writes lossless WAV.
+	Filter(ctx context.Context, src, dst string, chain []string) error
+	Analyze(ctx context.Context, path string, silenceDB float64) (AudioStats, error)
+}
+
+type FFmpeg struct {
+	Binary string
+	Probe  string
+}
+
+func (f FFmpeg) binary() string {
+	if f.Binary == "" {
+		return "ffmpeg"
+	}
+	return f.Binary
+}
+
+func (f FFmpeg) probe() string {
+	if f.Probe == "" {
+		return "ffprobe"
+	}
+	return f.Probe
+}
+
+// Detect asks ffprobe for the container, so a mislabeled extension does not matter.
+func (f FFmpeg) Detect(ctx context.Context, path string) (Format, error) {
+	cmd := exec.CommandContext(ctx, f.probe(), "-v", "error", "-show_entries", "format=format_name:stream=codec_name", "-of", "json", path)
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("ffprobe %s: %w", path, err)
+	}
+	return probeFormat(out, path)
+}
+
+// probeFormat maps ffprobe's JSON description of path to a Format.
+func probeFormat(out []byte, path string) (Format, error) {
+	var res struct {
+		Streams []struct {
+			CodecName string `json:"codec_name"`
+		} `json:"streams"`
+		Format struct {
+			FormatName string `json:"format_name"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &res); err != nil {
+		return "", fmt.Errorf("decode ffprobe output: %w", err)
+	}
+	codec := ""
+	if len(res.Streams) > 0 {
+		codec = res.Streams[0].CodecName
+	}
+
+	names := strings.Split(res.Format.FormatName, ",")
+	switch {
+	case contains(names, "webm") && codec == "opus":
+		return FormatWebM, nil
+	case contains(names, "wav"):
+		return FormatWAV, nil
+	case contains(names, "flac"):
+		return FormatFLAC, nil
+	case contains(names, "m4a"), contains(names, "mp4"):
+		return FormatM4A, nil
+	case contains(names, "ogg"):
+		return FormatOGG, nil
+	case contains(names, "mp3"):
+		return FormatMP3, nil
+	case contains(names, "webm"), contains(names, "matroska"):
+		return FormatMatroska, nil
+	}
+	return "", fmt.Errorf("unsupported audio format %q (codec %q) in %s", res.Format.FormatName, codec, path)
+}
+
+func (f FFmpeg) ToWebM(ctx context.Context, src, dst string, opts EncodeOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	args := []string{"-y", "-i", src, "-vn", "-c:a", "libopus", "-b:a", opts.Bitrate}
+	if opts.SampleRate > 0 {
+		args = append(args, "-ar", strconv.Itoa(opts.SampleRate))
+	}
+	if opts.Mono {
+		args = append(args, "-ac", "1")
+	}
+	// bitexact keeps the muxer from stamping the date, so the same input always hashes the same.
+	args = append(args, "-fflags", "+bitexact", "-flags:a", "+bitexact", "-f", "webm", dst)
+
+	cmd := exec.CommandContext(ctx, f.binary(), args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("ffmpeg: %v, out=%s", err, string(out))
+	}
+	return nil
+}
+
+func (f FFmpeg) Filter(ctx context.Context, src, dst string, chain []string) error {
+	cmd := exec.CommandContext(ctx, f.binary(), "-y", "-i", src, "-vn", "-af", strings.Join(chain, ","),
+		"-c:a", "pcm_s16le", "-fflags", "+bitexact", "-flags:a", "+bitexact", "-f", "wav", dst)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("ffmpeg: %v, out=%s", err, string(out))
+	}
+	return nil
+}
+
+func (f FFmpeg) Analyze(ctx context.Context, path string, silenceDB float64) (AudioStats, error) {
+	return Analyze(ctx, path, AnalyzeOptions{FFmpeg: f.binary(), FFprobe: f.probe(), SilenceDB: silenceDB})
+}
+
+// FakeTranscoder stands in for ffmpeg: it detects by extension, copies bytes instead of encoding or filtering and
+// reports Stats from Analyze. It lets the worker run where ffmpeg is not installed.
+type FakeTranscoder struct {
+	Stats AudioStats
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (f *FakeTranscoder) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func (f *FakeTranscoder) called(format string, args ...any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fmt.Sprintf(format, args...))
+}
+
+func (f *FakeTranscoder) Detect(ctx context.Context, path string) (Format, error) {
+	f.called("detect %s", path)
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	switch Format(ext) {
+	case FormatWAV, FormatFLAC, FormatM4A, FormatOGG, FormatMP3, FormatWebM:
+		return Format(ext), nil
+	case "opus":
+		return FormatOGG, nil
+	case "mkv", "mka":
+		return FormatMatroska, nil
+	}
+	return "", fmt.Errorf("unsupported audio format %q in %s", ext, path)
+}
+
+func (f *FakeTranscoder) ToWebM(ctx context.Context, src, dst string, opts EncodeOptions) error {
+	f.called("webm %s -> %s", src, dst)
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	return copyFile(src, dst)
+}
+
+func (f *FakeTranscoder) Filter(ctx context.Context, src, dst string, chain []string) error {
+	f.called("filter %s -> %s [%s]", src, dst, strings.Join(chain, ","))
+	return copyFile(src, dst)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (f *FakeTranscoder) Analyze(ctx context.Context, path string, silenceDB float64) (AudioStats, error) {
+	f.called("analyze %s", path)
+	if f.Stats != (AudioStats{}) {
+		return f.Stats, nil
+	}
+	return AudioStats{
+		Duration:   5 * time.Second,
+		SampleRate: 48000,
+		Channels:   1,
+		RMSdB:      -20,
+		PeakdB:     -3,
+	}, nil
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
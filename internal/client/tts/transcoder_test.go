@@ -0,0 +1,39 @@
+package tts
+
+import "testing"
+
+func TestProbeFormat(t *testing.T) {
+	probe := func(format, codec string) string {
+		return `{"programs":[],"streams":[{"codec_name":"` + codec + `"}],"format":{"format_name":"` + format + `"}}`
+	}
+	tests := []struct {
+		name    string
+		out     string
+		want    Format
+		wantErr bool
+	}{
+		{"webm opus", probe("matroska,webm", "opus"), FormatWebM, false},
+		{"webm vorbis", probe("matroska,webm", "vorbis"), FormatMatroska, false},
+		{"matroska aac", probe("matroska,webm", "aac"), FormatMatroska, false},
+		{"wav", probe("wav", "pcm_s16le"), FormatWAV, false},
+		{"flac", probe("flac", "flac"), FormatFLAC, false},
+		{"m4a", probe("mov,mp4,m4a,3gp,3g2,mj2", "aac"), FormatM4A, false},
+		{"ogg opus", probe("ogg", "opus"), FormatOGG, false},
+		{"ogg vorbis", probe("ogg", "vorbis"), FormatOGG, false},
+		{"mp3", probe("mp3", "mp3"), FormatMP3, false},
+		{"no streams", `{"format":{"format_name":"wav"}}`, FormatWAV, false},
+		{"unsupported", probe("avi", "pcm_s16le"), "", true},
+		{"garbage", "not json", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := probeFormat([]byte(tt.out), "a.rec")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("probeFormat = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,14 +1,9 @@
 package tts
 
 import (
-	"bytes"
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -21,11 +16,22 @@ import (
 )
 
 type Options struct {
-	Language string
-	Bitrate  string
+	Language   string
+	Transcoder Transcoder
+	Encode     EncodeOptions
 }
 
-func SynthesizeToWebM(session *model.Session, text string, opts Options) (string, error) {
+func (o *Options) defaults() error {
+	if o.Transcoder == nil {
+		o.Transcoder = FFmpeg{}
+	}
+	if o.Encode.Bitrate == "" {
+		o.Encode.Bitrate = "48k"
+	}
+	return o.Encode.validate()
+}
+
+func SynthesizeToWebM(ctx context.Context, session *model.Session, text string, opts Options) (string, error) {
 	log := logger.NewNamed(fmt.Sprintf("TTS - Account %d", session.AccIdx+1), session)
 	startAll := time.Now()
 
@@ -35,17 +41,20 @@ func SynthesizeToWebM(session *model.Session, text string, opts Options) (string
 	if opts.Language == "" {
 		opts.Language = "en"
 	}
-	if opts.Bitrate == "" {
-		opts.Bitrate = "48k"
-	}
-	if !validBitrate(opts.Bitrate) {
-		return "", fmt.Errorf("invalid bitrate: %s (use like 48k, 64k, 96k)", opts.Bitrate)
+	if err := opts.defaults(); err != nil {
+		return "", err
 	}
 
 	tmpDir, err := os.MkdirTemp("", "tts-*")
 	if err != nil {
 		return "", fmt.Errorf("mktemp: %w", err)
 	}
+	ok := false
+	defer func() {
+		if !ok {
+			os.RemoveAll(tmpDir)
+		}
+	}()
 
 	base := fmt.Sprintf("%s_%d", mapLang(opts.Language), time.Now().UnixNano())
 	mp3Path := filepath.Join(tmpDir, base+".mp3")
@@ -64,12 +73,38 @@ func SynthesizeToWebM(session *model.Session, text string, opts Options) (string
 		return "", fmt.Errorf("mp3 not found: %w", err)
 	}
 
-	cmd := exec.Command("ffmpeg", "-y", "-i", mp3Path, "-c:a", "libopus", "-b:a", opts.Bitrate, webmPath)
-	if out, err := cmd.CombinedOutput(); err != nil {
-		return "", fmt.Errorf("ffmpeg: %v, out=%s", err, string(out))
+	if err := opts.Transcoder.ToWebM(ctx, mp3Path, webmPath, opts.Encode); err != nil {
+		return "", err
 	}
 
 	log.JustLog(fmt.Sprintf("[TTS] DONE mp3+webm in %s (tmp=%s)", time.Since(startAll), tmpDir))
+	ok = true
+	return webmPath, nil
+}
+
+func ConvertToWebM(ctx context.Context, session *model.Session, srcPath string, opts Options) (string, error) {
+	log := logger.NewNamed(fmt.Sprintf("TTS - Account %d", session.AccIdx+1), session)
+
+	if err := opts.defaults(); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(srcPath); err != nil {
+		return "", fmt.Errorf("recording not found: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "rec-*")
+	if err != nil {
+		return "", fmt.Errorf("mktemp: %w", err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(srcPath), filepath.Ext(srcPath))
+	webmPath := filepath.Join(tmpDir, base+".webm")
+
+	log.JustLog(fmt.Sprintf("[REC] Converting %s -> %s", srcPath, webmPath))
+	if err := opts.Transcoder.ToWebM(ctx, srcPath, webmPath, opts.Encode); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", err
+	}
 	return webmPath, nil
 }
 
@@ -90,58 +125,7 @@ func validBitrate(b string) bool {
 	return reBitrate.MatchString(strings.ToLower(strings.TrimSpace(b)))
 }
 
-func PutPresignedWebM(url string, webmPath string) error {
-	f, err := os.Open(webmPath)
-	if err != nil {
-		return fmt.Errorf("open webm: %w", err)
-	}
-	defer f.Close()
-
-	h := sha256.New()
-	body := &bytes.Buffer{}
-	if _, err := io.Copy(io.MultiWriter(h, body), f); err != nil {
-		return fmt.Errorf("read webm: %w", err)
-	}
-
-	req, err := http.NewRequest("PUT", url, bytes.NewReader(body.Bytes()))
-	if err != nil {
-		return fmt.Errorf("new req: %w", err)
-	}
-	req.Header.Set("Content-Type", "audio/webm")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("put presigned: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 && resp.StatusCode != 204 {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("put presigned failed: %s, %s", resp.Status, string(b))
-	}
-
-	return nil
-}
-
 type FileDigest struct {
 	HashHex  string
 	FileSize int
 }
-
-func ComputeSHA256AndSize(path string) (FileDigest, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return FileDigest{}, fmt.Errorf("open: %w", err)
-	}
-	defer f.Close()
-
-	h := sha256.New()
-	n, err := io.Copy(h, f)
-	if err != nil {
-		return FileDigest{}, fmt.Errorf("read: %w", err)
-	}
-	return FileDigest{
-		HashHex:  hex.EncodeToString(h.Sum(nil)),
-		FileSize: int(n),
-	}, nil
-}
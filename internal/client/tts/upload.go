@@ -0,0 +1,186 @@
+package tts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/utils/exception"
+)
+
+type PutOptions struct {
+	Client *http.Client
+	// BytesPerSecond caps the upload rate; zero means unlimited.
+	BytesPerSecond int64
+	// IdleTimeout aborts the upload once no byte has been sent for this long, or no response has arrived
+	// this long after the last one; zero means no limit. It does not bound the upload as a whole, so a
+	// throttled upload of any size can finish.
+	IdleTimeout time.Duration
+	Progress    func(sent, total int64)
+}
+
+var errStalled = errors.New("upload stalled")
+
+// PutPresignedWebM streams the file to a presigned URL once, hashing it on the way out.
+func PutPresignedWebM(ctx context.Context, presignedURL, webmPath string, opts PutOptions) (FileDigest, error) {
+	f, err := os.Open(webmPath)
+	if err != nil {
+		return FileDigest{}, fmt.Errorf("open webm: %w", err)
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return FileDigest{}, fmt.Errorf("stat webm: %w", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var idle *time.Timer
+	if opts.IdleTimeout > 0 {
+		idle = time.AfterFunc(opts.IdleTimeout, func() {
+			cancel(fmt.Errorf("%w: no progress for %s", errStalled, opts.IdleTimeout))
+		})
+		defer idle.Stop()
+	}
+
+	body := &uploadReader{
+		ctx:      ctx,
+		idle:     idle,
+		timeout:  opts.IdleTimeout,
+		r:        f,
+		h:        sha256.New(),
+		total:    st.Size(),
+		limit:    opts.BytesPerSecond,
+		progress: opts.Progress,
+		start:    time.Now(),
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, presignedURL, body)
+	if err != nil {
+		return FileDigest{}, fmt.Errorf("new req: %w", err)
+	}
+	req.ContentLength = st.Size()
+	req.Header.Set("Content-Type", "audio/webm")
+
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// A stall cancels the request; report it as a transport failure rather than a cancellation.
+		if cause := context.Cause(ctx); errors.Is(cause, errStalled) {
+			return FileDigest{}, exception.Wrap(exception.ClassTransport, fmt.Errorf("put presigned: %w", cause))
+		}
+		return FileDigest{}, fmt.Errorf("put presigned: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return FileDigest{}, exception.Wrap(exception.ForStatus(resp.StatusCode, string(b)),
+			fmt.Errorf("put presigned failed: %s, %s", resp.Status, string(b)))
+	}
+	if body.sent != body.total {
+		return FileDigest{}, exception.Wrap(exception.ClassTransport,
+			fmt.Errorf("put presigned: sent %d of %d bytes", body.sent, body.total))
+	}
+	body.report(true)
+
+	return FileDigest{
+		HashHex:  hex.EncodeToString(body.h.Sum(nil)),
+		FileSize: int(body.sent),
+	}, nil
+}
+
+// PresignedExpiry reads the expiry of an S3-style presigned URL from X-Amz-Date and X-Amz-Expires.
+func PresignedExpiry(presignedURL string) (time.Time, bool) {
+	u, err := url.Parse(presignedURL)
+	if err != nil {
+		return time.Time{}, false
+	}
+	q := u.Query()
+	signed, err := time.Parse("20060102T150405Z", q.Get("X-Amz-Date"))
+	if err != nil {
+		return time.Time{}, false
+	}
+	secs, err := strconv.Atoi(q.Get("X-Amz-Expires"))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return signed.Add(time.Duration(secs) * time.Second), true
+}
+
+const progressEvery = 250 * time.Millisecond
+
+type uploadReader struct {
+	ctx      context.Context
+	idle     *time.Timer
+	timeout  time.Duration
+	r        io.Reader
+	h        hash.Hash
+	sent     int64
+	total    int64
+	limit    int64
+	progress func(sent, total int64)
+	start    time.Time
+	reported time.Time
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	if u.limit > 0 && int64(len(p)) > u.limit {
+		p = p[:u.limit]
+	}
+	n, err := u.r.Read(p)
+	if n > 0 {
+		u.h.Write(p[:n])
+		u.sent += int64(n)
+		if u.idle != nil {
+			u.idle.Reset(u.timeout)
+		}
+		u.report(false)
+		if werr := u.throttle(); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (u *uploadReader) throttle() error {
+	if u.limit <= 0 {
+		return nil
+	}
+	due := time.Duration(float64(u.sent) / float64(u.limit) * float64(time.Second))
+	wait := due - time.Since(u.start)
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-u.ctx.Done():
+		return u.ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (u *uploadReader) report(final bool) {
+	if u.progress == nil {
+		return
+	}
+	if !final && time.Since(u.reported) < progressEvery {
+		return
+	}
+	u.reported = time.Now()
+	u.progress(u.sent, u.total)
+}
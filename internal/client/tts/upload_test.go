@@ -0,0 +1,64 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/utils/exception"
+)
+
+func writeAudio(t *testing.T, size int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "a.webm")
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPutThrottledBeyondIdleTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	t.Cleanup(srv.Close)
+
+	// 6 KiB at 4 KiB/s takes about 1.5s, longer than the idle timeout, but never stalls that long.
+	start := time.Now()
+	dg, err := PutPresignedWebM(context.Background(), srv.URL, writeAudio(t, 6<<10), PutOptions{
+		BytesPerSecond: 4 << 10,
+		IdleTimeout:    1200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("PutPresignedWebM: %v", err)
+	}
+	if dg.FileSize != 6<<10 {
+		t.Errorf("FileSize = %d, want %d", dg.FileSize, 6<<10)
+	}
+	if elapsed := time.Since(start); elapsed < 1200*time.Millisecond {
+		t.Errorf("upload took %s, want it throttled past the idle timeout", elapsed)
+	}
+}
+
+func TestPutAbortsStalledUpload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	_, err := PutPresignedWebM(ctx, srv.URL, writeAudio(t, 1<<10), PutOptions{IdleTimeout: 100 * time.Millisecond})
+	if !errors.Is(err, exception.ErrTransport) {
+		t.Fatalf("err = %v, want a transport error", err)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v matches context.Canceled, the worker would stop instead of retrying", err)
+	}
+}
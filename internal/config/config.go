@@ -0,0 +1,568 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/model"
+)
+
+const DefaultPath = "configs/config.json"
+
+const (
+	TranscoderFFmpeg = "ffmpeg"
+	TranscoderFake   = "fake"
+)
+
+type Config struct {
+	Paths      Paths           `json:"paths"`
+	Endpoints  model.Endpoints `json:"endpoints"`
+	HTTP       HTTP            `json:"http"`
+	Retry      Retry           `json:"retry"`
+	Errors     Errors          `json:"errors"`
+	Loop       Loop            `json:"loop"`
+	Outbox     Outbox          `json:"outbox"`
+	Audio      Audio           `json:"audio"`
+	Preprocess Preprocess      `json:"preprocess"`
+	Quality    Quality         `json:"quality"`
+	Log        Log             `json:"log"`
+	// TestMode runs against an in-process stand-in server with throwaway state.
+	TestMode bool `json:"test_mode"`
+}
+
+type Paths struct {
+	Accounts    string `json:"accounts"`
+	Credentials string `json:"credentials"`
+	Log         string `json:"log"`
+	TokenDir    string `json:"token_dir"`
+	GmailToken  string `json:"gmail_token"`
+	Recordings  string `json:"recordings"`
+	Sessions    string `json:"sessions"`
+	Ledger      string `json:"ledger"`
+	Review      string `json:"review"`
+	Outbox      string `json:"outbox"`
+}
+
+type HTTP struct {
+	Timeout               Duration `json:"timeout"`
+	DialTimeout           Duration `json:"dial_timeout"`
+	TLSHandshakeTimeout   Duration `json:"tls_handshake_timeout"`
+	ResponseHeaderTimeout Duration `json:"response_header_timeout"`
+	IdleConnTimeout       Duration `json:"idle_conn_timeout"`
+	// UploadTimeout is how long a presigned upload may go without progress, not a limit on the whole
+	// upload, so it holds for any file size under UploadLimitKiB.
+	UploadTimeout Duration `json:"upload_timeout"`
+	// UploadLimitKiB caps presigned uploads in KiB per second; zero means unlimited.
+	UploadLimitKiB int `json:"upload_limit_kib"`
+	// BreakerThreshold consecutive server or transport failures open a host's circuit for BreakerCooldown;
+	// zero disables the breaker.
+	BreakerThreshold int      `json:"breaker_threshold"`
+	BreakerCooldown  Duration `json:"breaker_cooldown"`
+}
+
+// Retry covers two layers. Attempts, BaseDelay and MaxDelay govern retries of a single API request with
+// exponential back-off; the per-error durations are how long the worker waits once a step has failed anyway.
+// Forbidden is the wait for quota_exhausted and ClientError the wait for client_error and fatal.
+type Retry struct {
+	Attempts     int      `json:"attempts"`
+	BaseDelay    Duration `json:"base_delay"`
+	MaxDelay     Duration `json:"max_delay"`
+	Unauthorized Duration `json:"unauthorized"`
+	Forbidden    Duration `json:"forbidden"`
+	NotFound     Duration `json:"not_found"`
+	RateLimited  Duration `json:"rate_limited"`
+	ServerError  Duration `json:"server_error"`
+	ClientError  Duration `json:"client_error"`
+	Transport    Duration `json:"transport"`
+}
+
+const (
+	ActionRetry        = "retry"
+	ActionRelogin      = "relogin"
+	ActionSkipCampaign = "skip_campaign"
+	ActionPauseAccount = "pause_account"
+	ActionStop         = "stop"
+)
+
+// Errors maps each error class to what the worker does when a step fails with it. StepRetries bounds how
+// often one campaign is retried in a cycle before the worker moves on.
+type Errors struct {
+	AuthExpired    string `json:"auth_expired"`
+	RateLimited    string `json:"rate_limited"`
+	QuotaExhausted string `json:"quota_exhausted"`
+	NotFound       string `json:"not_found"`
+	Server         string `json:"server"`
+	Transport      string `json:"transport"`
+	CorruptUpload  string `json:"corrupt_upload"`
+	ClientError    string `json:"client_error"`
+	Fatal          string `json:"fatal"`
+	StepRetries    int    `json:"step_retries"`
+}
+
+// Action returns the action for a class named like the JSON keys, e.g. "rate_limited".
+func (e Errors) Action(class string) string {
+	switch class {
+	case "auth_expired":
+		return e.AuthExpired
+	case "rate_limited":
+		return e.RateLimited
+	case "quota_exhausted":
+		return e.QuotaExhausted
+	case "not_found":
+		return e.NotFound
+	case "server":
+		return e.Server
+	case "transport":
+		return e.Transport
+	case "corrupt_upload":
+		return e.CorruptUpload
+	case "client_error":
+		return e.ClientError
+	}
+	return e.Fatal
+}
+
+type Loop struct {
+	Interval Duration `json:"interval"`
+}
+
+type Outbox struct {
+	MaxAttempts int `json:"max_attempts"`
+}
+
+type Audio struct {
+	Source  string `json:"source"`
+	Bitrate string `json:"bitrate"`
+	// SampleRate resamples encoded audio when set; zero keeps the source rate.
+	SampleRate int  `json:"sample_rate"`
+	Mono       bool `json:"mono"`
+	// Transcoder is "ffmpeg", or "fake" to copy audio unchanged without ffmpeg.
+	Transcoder string `json:"transcoder"`
+	FFmpeg     string `json:"ffmpeg"`
+	FFprobe    string `json:"ffprobe"`
+}
+
+// Preprocess selects the clean-up stages run on recordings before they are encoded; all are off by default.
+type Preprocess struct {
+	HighPass    bool    `json:"high_pass"`
+	HighPassHz  int     `json:"high_pass_hz"`
+	TrimSilence bool    `json:"trim_silence"`
+	SilenceDB   float64 `json:"silence_db"`
+	Loudnorm    bool    `json:"loudnorm"`
+	TargetLUFS  float64 `json:"target_lufs"`
+	Resample    bool    `json:"resample"`
+	SampleRate  int     `json:"sample_rate"`
+}
+
+// Quality holds the pre-flight limits a recording must meet before it is uploaded.
+type Quality struct {
+	Enabled            bool     `json:"enabled"`
+	MinDuration        Duration `json:"min_duration"`
+	MaxDuration        Duration `json:"max_duration"`
+	MinSampleRate      int      `json:"min_sample_rate"`
+	MaxChannels        int      `json:"max_channels"`
+	MinRMSdB           float64  `json:"min_rms_db"`
+	MaxClippingRatio   float64  `json:"max_clipping_ratio"`
+	SilenceDB          float64  `json:"silence_db"`
+	MaxLeadingSilence  Duration `json:"max_leading_silence"`
+	MaxTrailingSilence Duration `json:"max_trailing_silence"`
+	MinWordsPerSecond  float64  `json:"min_words_per_second"`
+	MaxWordsPerSecond  float64  `json:"max_words_per_second"`
+}
+
+type Log struct {
+	Level string `json:"level"`
+}
+
+func Default() *Config {
+	return &Config{
+		Paths: Paths{
+			Accounts:    "accounts/accounts.json",
+			Credentials: "configs/credentials.json",
+			Log:         "logs/app.log",
+			TokenDir:    "accounts",
+			GmailToken:  "accounts/{email}-data.json",
+			Recordings:  "recordings",
+			Sessions:    "sessions",
+			Ledger:      "data/ledger.jsonl",
+			Review:      "data/review.json",
+			Outbox:      "data/outbox",
+		},
+		Endpoints: model.Endpoints{}.WithDefaults(),
+		HTTP: HTTP{
+			Timeout:               Duration(60 * time.Second),
+			DialTimeout:           Duration(10 * time.Second),
+			TLSHandshakeTimeout:   Duration(10 * time.Second),
+			ResponseHeaderTimeout: Duration(30 * time.Second),
+			IdleConnTimeout:       Duration(90 * time.Second),
+			UploadTimeout:         Duration(time.Minute),
+			BreakerThreshold:      5,
+			BreakerCooldown:       Duration(30 * time.Second),
+		},
+		Retry: Retry{
+			Attempts:     4,
+			BaseDelay:    Duration(500 * time.Millisecond),
+			MaxDelay:     Duration(30 * time.Second),
+			Unauthorized: Duration(3 * time.Second),
+			Forbidden:    Duration(30 * time.Second),
+			NotFound:     Duration(30 * time.Second),
+			RateLimited:  Duration(60 * time.Second),
+			ServerError:  Duration(15 * time.Second),
+			ClientError:  Duration(30 * time.Second),
+			Transport:    Duration(10 * time.Second),
+		},
+		Errors: Errors{
+			AuthExpired:    ActionRelogin,
+			RateLimited:    ActionRetry,
+			QuotaExhausted: ActionSkipCampaign,
+			NotFound:       ActionSkipCampaign,
+			Server:         ActionRetry,
+			Transport:      ActionRetry,
+			CorruptUpload:  ActionSkipCampaign,
+			ClientError:    ActionSkipCampaign,
+			Fatal:          ActionPauseAccount,
+			StepRetries:    3,
+		},
+		Outbox: Outbox{
+			MaxAttempts: 5,
+		},
+		Loop: Loop{
+			Interval: Duration(1_000_000 * time.Millisecond),
+		},
+		Audio: Audio{
+			Source:     model.SourceRecording,
+			Bitrate:    "48k",
+			Transcoder: TranscoderFFmpeg,
+			FFmpeg:     "ffmpeg",
+			FFprobe:    "ffprobe",
+		},
+		Preprocess: Preprocess{
+			HighPassHz: 80,
+			SilenceDB:  -50,
+			TargetLUFS: -16,
+			SampleRate: 48000,
+		},
+		Quality: Quality{
+			Enabled:            true,
+			MinDuration:        Duration(1 * time.Second),
+			MaxDuration:        Duration(60 * time.Second),
+			MinSampleRate:      16000,
+			MaxChannels:        2,
+			MinRMSdB:           -45,
+			MaxClippingRatio:   0.001,
+			SilenceDB:          -50,
+			MaxLeadingSilence:  Duration(3 * time.Second),
+			MaxTrailingSilence: Duration(3 * time.Second),
+			MinWordsPerSecond:  0.5,
+			MaxWordsPerSecond:  5,
+		},
+		Log: Log{
+			Level: "debug",
+		},
+	}
+}
+
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !explicit {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("read config %s: %w", path, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("decode config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func Resolve(path string, fs *flag.FlagSet) (*Config, error) {
+	cfg, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.ApplyEnv(); err != nil {
+		return nil, err
+	}
+	if fs != nil {
+		if err := cfg.ApplyFlags(fs); err != nil {
+			return nil, err
+		}
+	}
+	cfg.Endpoints = cfg.Endpoints.WithDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) GmailTokenPath(email string) string {
+	return strings.ReplaceAll(c.Paths.GmailToken, "{email}", email)
+}
+
+// CheckSource refuses the tts source unless the bot runs in test mode or SyntheticAllowed accepts the
+// endpoints, so synthetic voices never reach the production API.
+func (c *Config) CheckSource() error {
+	if c.Audio.Source != model.SourceTTS || c.TestMode || SyntheticAllowed(c.Endpoints) {
+		return nil
+	}
+	return fmt.Errorf("config: audio.source %q is only allowed against a local or sandbox endpoint, endpoints.poseidon is %s (use -source recording, -test-mode to run against the stand-in server, or -sandbox for a non-production server)", c.Audio.Source, c.Endpoints.Poseidon)
+}
+
+// SyntheticAllowed reports whether synthetic audio may be sent to e: its Poseidon endpoint is on this
+// machine, or it is marked as a sandbox and is not a production host.
+func SyntheticAllowed(e model.Endpoints) bool {
+	return IsLocal(e.Poseidon) || (e.Sandbox && !IsProduction(e.Poseidon))
+}
+
+// IsLocal reports whether rawURL points at this machine.
+func IsLocal(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// productionDomain hosts the production Poseidon API.
+const productionDomain = "storyapis.com"
+
+// IsProduction reports whether rawURL points at the production API's domain.
+func IsProduction(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == productionDomain || strings.HasSuffix(host, "."+productionDomain)
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("config: "+format, args...))
+	}
+
+	if strings.TrimSpace(c.Paths.Accounts) == "" {
+		add("paths.accounts is required")
+	}
+	if strings.TrimSpace(c.Paths.Credentials) == "" {
+		add("paths.credentials is required")
+	}
+	if strings.TrimSpace(c.Paths.Log) == "" {
+		add("paths.log is required")
+	}
+	if strings.TrimSpace(c.Paths.TokenDir) == "" {
+		add("paths.token_dir is required")
+	}
+	if strings.TrimSpace(c.Paths.Ledger) == "" {
+		add("paths.ledger is required")
+	}
+	if strings.TrimSpace(c.Paths.Review) == "" {
+		add("paths.review is required")
+	}
+	if strings.TrimSpace(c.Paths.Outbox) == "" {
+		add("paths.outbox is required")
+	}
+	if !strings.Contains(c.Paths.GmailToken, "{email}") {
+		add("paths.gmail_token must contain the {email} placeholder, got %q", c.Paths.GmailToken)
+	}
+
+	for _, e := range []struct{ name, url string }{
+		{"endpoints.poseidon", c.Endpoints.Poseidon},
+		{"endpoints.dynamic", c.Endpoints.Dynamic},
+	} {
+		if !strings.HasPrefix(e.url, "http://") && !strings.HasPrefix(e.url, "https://") {
+			add("%s must be an http(s) URL, got %q", e.name, e.url)
+		}
+	}
+	if c.Endpoints.Sandbox && IsProduction(c.Endpoints.Poseidon) {
+		add("endpoints.sandbox cannot be set for the production API %s", c.Endpoints.Poseidon)
+	}
+
+	for _, t := range []struct {
+		name string
+		d    Duration
+	}{
+		{"http.timeout", c.HTTP.Timeout},
+		{"http.dial_timeout", c.HTTP.DialTimeout},
+		{"http.tls_handshake_timeout", c.HTTP.TLSHandshakeTimeout},
+		{"http.response_header_timeout", c.HTTP.ResponseHeaderTimeout},
+		{"http.idle_conn_timeout", c.HTTP.IdleConnTimeout},
+		{"http.upload_timeout", c.HTTP.UploadTimeout},
+		{"http.breaker_cooldown", c.HTTP.BreakerCooldown},
+	} {
+		if t.d <= 0 {
+			add("%s must be greater than zero", t.name)
+		}
+	}
+	if c.HTTP.UploadLimitKiB < 0 {
+		add("http.upload_limit_kib must not be negative")
+	}
+	// A throttled upload sends at most one second's worth of data before it pauses.
+	if c.HTTP.UploadLimitKiB > 0 && c.HTTP.UploadTimeout > 0 && c.HTTP.UploadTimeout.Std() <= 2*time.Second {
+		add("http.upload_timeout must be longer than 2s when http.upload_limit_kib is set, a throttled upload pauses up to a second between writes")
+	}
+	if c.HTTP.BreakerThreshold < 0 {
+		add("http.breaker_threshold must not be negative")
+	}
+	for _, r := range []struct {
+		name string
+		d    Duration
+	}{
+		{"retry.unauthorized", c.Retry.Unauthorized},
+		{"retry.forbidden", c.Retry.Forbidden},
+		{"retry.not_found", c.Retry.NotFound},
+		{"retry.rate_limited", c.Retry.RateLimited},
+		{"retry.server_error", c.Retry.ServerError},
+		{"retry.client_error", c.Retry.ClientError},
+		{"retry.transport", c.Retry.Transport},
+	} {
+		if r.d < 0 {
+			add("%s must not be negative", r.name)
+		}
+	}
+	if c.Retry.Attempts < 1 {
+		add("retry.attempts must be at least 1")
+	}
+	if c.Retry.BaseDelay <= 0 {
+		add("retry.base_delay must be greater than zero")
+	}
+	if c.Retry.MaxDelay < c.Retry.BaseDelay {
+		add("retry.max_delay must not be less than retry.base_delay")
+	}
+	for _, e := range []struct{ name, action string }{
+		{"errors.auth_expired", c.Errors.AuthExpired},
+		{"errors.rate_limited", c.Errors.RateLimited},
+		{"errors.quota_exhausted", c.Errors.QuotaExhausted},
+		{"errors.not_found", c.Errors.NotFound},
+		{"errors.server", c.Errors.Server},
+		{"errors.transport", c.Errors.Transport},
+		{"errors.corrupt_upload", c.Errors.CorruptUpload},
+		{"errors.client_error", c.Errors.ClientError},
+		{"errors.fatal", c.Errors.Fatal},
+	} {
+		switch e.action {
+		case ActionRetry, ActionRelogin, ActionSkipCampaign, ActionPauseAccount, ActionStop:
+		default:
+			add("%s must be one of retry, relogin, skip_campaign, pause_account or stop, got %q", e.name, e.action)
+		}
+	}
+	if c.Errors.StepRetries < 0 {
+		add("errors.step_retries must not be negative")
+	}
+	if c.Outbox.MaxAttempts < 1 {
+		add("outbox.max_attempts must be at least 1")
+	}
+	if c.Loop.Interval <= 0 {
+		add("loop.interval must be greater than zero")
+	}
+
+	switch c.Audio.Source {
+	case model.SourceTTS, model.SourceRecording:
+	default:
+		add("audio.source must be %q or %q, got %q", model.SourceTTS, model.SourceRecording, c.Audio.Source)
+	}
+	if strings.TrimSpace(c.Audio.Bitrate) == "" {
+		add("audio.bitrate is required")
+	}
+	if c.Audio.SampleRate < 0 {
+		add("audio.sample_rate must not be negative")
+	}
+	switch c.Audio.Transcoder {
+	case TranscoderFFmpeg, TranscoderFake:
+	default:
+		add("audio.transcoder must be %q or %q, got %q", TranscoderFFmpeg, TranscoderFake, c.Audio.Transcoder)
+	}
+	if strings.TrimSpace(c.Audio.FFmpeg) == "" {
+		add("audio.ffmpeg is required")
+	}
+	if strings.TrimSpace(c.Audio.FFprobe) == "" {
+		add("audio.ffprobe is required")
+	}
+
+	p := c.Preprocess
+	if p.HighPass && p.HighPassHz <= 0 {
+		add("preprocess.high_pass_hz must be greater than zero")
+	}
+	if p.TrimSilence && p.SilenceDB >= 0 {
+		add("preprocess.silence_db must be negative, got %g", p.SilenceDB)
+	}
+	if p.Loudnorm && (p.TargetLUFS < -70 || p.TargetLUFS > -5) {
+		add("preprocess.target_lufs must be between -70 and -5, got %g", p.TargetLUFS)
+	}
+	if p.Resample && p.SampleRate <= 0 {
+		add("preprocess.sample_rate must be greater than zero")
+	}
+
+	q := c.Quality
+	if q.MinDuration < 0 || q.MaxDuration < 0 || q.MaxLeadingSilence < 0 || q.MaxTrailingSilence < 0 {
+		add("quality durations must not be negative")
+	}
+	if q.MaxDuration > 0 && q.MinDuration > q.MaxDuration {
+		add("quality.min_duration must not exceed quality.max_duration")
+	}
+	if q.MaxClippingRatio < 0 || q.MaxClippingRatio > 1 {
+		add("quality.max_clipping_ratio must be between 0 and 1, got %g", q.MaxClippingRatio)
+	}
+	if q.SilenceDB >= 0 {
+		add("quality.silence_db must be negative, got %g", q.SilenceDB)
+	}
+	if q.MaxWordsPerSecond > 0 && q.MinWordsPerSecond > q.MaxWordsPerSecond {
+		add("quality.min_words_per_second must not exceed quality.max_words_per_second")
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info":
+	default:
+		add("log.level must be \"debug\" or \"info\", got %q", c.Log.Level)
+	}
+
+	return errors.Join(errs...)
+}
+
+type Duration time.Duration
+
+func (d Duration) Std() time.Duration { return time.Duration(d) }
+
+func (d Duration) String() string { return time.Duration(d).String() }
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\" or \"5m\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
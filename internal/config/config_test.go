@@ -0,0 +1,94 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/model"
+)
+
+func TestIsLocal(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"http://localhost:8080", true},
+		{"http://LOCALHOST", true},
+		{"http://poseidon.localhost", true},
+		{"http://127.0.0.1:8798", true},
+		{"http://127.8.9.10", true},
+		{"http://[::1]:8798", true},
+		{"http://10.0.0.5", false},
+		{"http://192.168.1.20:8080", false},
+		{"http://172.16.0.1", false},
+		{"http://poseidon.local", false},
+		{"http://poseidon.test", false},
+		{"http://localhost.example.com", false},
+		{model.DefaultPoseidonURL, false},
+		{"://bad", false},
+	}
+	for _, tt := range tests {
+		if got := IsLocal(tt.url); got != tt.want {
+			t.Errorf("IsLocal(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		poseidon string
+		sandbox  bool
+		testMode bool
+		wantErr  bool
+	}{
+		{"recording against production", model.SourceRecording, model.DefaultPoseidonURL, false, false, false},
+		{"tts against production", model.SourceTTS, model.DefaultPoseidonURL, false, false, true},
+		{"tts against production marked sandbox", model.SourceTTS, model.DefaultPoseidonURL, true, false, true},
+		{"tts against another storyapis.com host", model.SourceTTS, "https://staging.storyapis.com", true, false, true},
+		{"tts against loopback", model.SourceTTS, "http://127.0.0.1:8798", false, false, false},
+		{"tts against localhost", model.SourceTTS, "http://localhost:8798", false, false, false},
+		{"tts against a private IP", model.SourceTTS, "http://192.168.1.20:8798", false, false, true},
+		{"tts against a .local host", model.SourceTTS, "http://poseidon.local", false, false, true},
+		{"tts against a sandbox", model.SourceTTS, "http://192.168.1.20:8798", true, false, false},
+		{"tts in test mode", model.SourceTTS, model.DefaultPoseidonURL, false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			c.Audio.Source = tt.source
+			c.Endpoints.Poseidon = tt.poseidon
+			c.Endpoints.Sandbox = tt.sandbox
+			c.TestMode = tt.testMode
+			if err := c.CheckSource(); (err != nil) != tt.wantErr {
+				t.Fatalf("CheckSource() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsProductionSandbox(t *testing.T) {
+	c := Default()
+	c.Endpoints.Sandbox = true
+	if err := c.Validate(); err == nil {
+		t.Fatal("Validate accepted endpoints.sandbox for the production API")
+	}
+	c.Endpoints.Poseidon = "http://192.168.1.20:8798"
+	if err := c.Validate(); err != nil {
+		t.Fatalf("Validate rejected a sandbox on another host: %v", err)
+	}
+}
+
+func TestValidateUploadTimeoutUnderLimit(t *testing.T) {
+	c := Default()
+	c.HTTP.UploadLimitKiB = 64
+	c.HTTP.UploadTimeout = Duration(time.Second)
+	if err := c.Validate(); err == nil {
+		t.Fatal("Validate accepted an upload timeout shorter than a throttle pause")
+	}
+	c.HTTP.UploadTimeout = Duration(time.Minute)
+	if err := c.Validate(); err != nil {
+		t.Fatalf("Validate rejected a throttled upload with a 1m idle timeout: %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, v string) error
+}
+
+var settings = []setting{
+	{"accounts", "POSEIDON_ACCOUNTS", "accounts file", setString(func(c *Config) *string { return &c.Paths.Accounts })},
+	{"credentials", "POSEIDON_CREDENTIALS", "Gmail OAuth client credentials file", setString(func(c *Config) *string { return &c.Paths.Credentials })},
+	{"log-file", "POSEIDON_LOG_FILE", "log file", setString(func(c *Config) *string { return &c.Paths.Log })},
+	{"token-dir", "POSEIDON_TOKEN_DIR", "directory for stored JWTs", setString(func(c *Config) *string { return &c.Paths.TokenDir })},
+	{"gmail-token", "POSEIDON_GMAIL_TOKEN", "Gmail token path pattern, {email} is replaced", setString(func(c *Config) *string { return &c.Paths.GmailToken })},
+	{"recordings", "POSEIDON_RECORDINGS", "base directory holding per-account recordings", setString(func(c *Config) *string { return &c.Paths.Recordings })},
+	{"sessions", "POSEIDON_SESSIONS", "recording session directory (export/submit)", setString(func(c *Config) *string { return &c.Paths.Sessions })},
+	{"ledger", "POSEIDON_LEDGER", "submission ledger file", setString(func(c *Config) *string { return &c.Paths.Ledger })},
+	{"review", "POSEIDON_REVIEW", "review state file for flagged submissions", setString(func(c *Config) *string { return &c.Paths.Review })},
+	{"outbox", "POSEIDON_OUTBOX", "directory holding in-flight uploads", setString(func(c *Config) *string { return &c.Paths.Outbox })},
+	{"poseidon-url", "POSEIDON_URL", "Poseidon API base URL", setString(func(c *Config) *string { return &c.Endpoints.Poseidon })},
+	{"dynamic-url", "POSEIDON_DYNAMIC_URL", "Dynamic Auth API base URL", setString(func(c *Config) *string { return &c.Endpoints.Dynamic })},
+	{"sandbox", "POSEIDON_SANDBOX", "treat the Poseidon URL as a non-production server that may receive synthetic audio", setBool(func(c *Config) *bool { return &c.Endpoints.Sandbox })},
+	{"http-timeout", "POSEIDON_HTTP_TIMEOUT", "HTTP request timeout, e.g. 60s", setDuration(func(c *Config) *Duration { return &c.HTTP.Timeout })},
+	{"upload-timeout", "POSEIDON_UPLOAD_TIMEOUT", "abort a presigned upload after this long without progress, e.g. 1m", setDuration(func(c *Config) *Duration { return &c.HTTP.UploadTimeout })},
+	{"upload-limit", "POSEIDON_UPLOAD_LIMIT", "upload bandwidth limit in KiB/s, 0 for none", setInt(func(c *Config) *int { return &c.HTTP.UploadLimitKiB })},
+	{"breaker-threshold", "POSEIDON_BREAKER_THRESHOLD", "consecutive server or transport failures that open a host's circuit, 0 disables", setInt(func(c *Config) *int { return &c.HTTP.BreakerThreshold })},
+	{"breaker-cooldown", "POSEIDON_BREAKER_COOLDOWN", "how long an open circuit fails fast before a probe, e.g. 30s", setDuration(func(c *Config) *Duration { return &c.HTTP.BreakerCooldown })},
+	{"retry-attempts", "POSEIDON_RETRY_ATTEMPTS", "tries per API request before the error reaches the worker", setInt(func(c *Config) *int { return &c.Retry.Attempts })},
+	{"retry-max-delay", "POSEIDON_RETRY_MAX_DELAY", "cap on the back-off between API request retries, e.g. 30s", setDuration(func(c *Config) *Duration { return &c.Retry.MaxDelay })},
+	{"step-retries", "POSEIDON_STEP_RETRIES", "retries of a failed campaign per cycle before moving on", setInt(func(c *Config) *int { return &c.Errors.StepRetries })},
+	{"interval", "POSEIDON_LOOP_INTERVAL", "sleep between account cycles, e.g. 15m", setDuration(func(c *Config) *Duration { return &c.Loop.Interval })},
+	{"upload-attempts", "POSEIDON_UPLOAD_ATTEMPTS", "failed attempts before an upload is dead-lettered", setInt(func(c *Config) *int { return &c.Outbox.MaxAttempts })},
+	{"source", "POSEIDON_SOURCE", "audio source: tts or recording", setString(func(c *Config) *string { return &c.Audio.Source })},
+	{"bitrate", "POSEIDON_BITRATE", "Opus bitrate, e.g. 48k", setString(func(c *Config) *string { return &c.Audio.Bitrate })},
+	{"sample-rate", "POSEIDON_SAMPLE_RATE", "resample encoded audio to this rate in Hz, 0 keeps the source", setInt(func(c *Config) *int { return &c.Audio.SampleRate })},
+	{"mono", "POSEIDON_MONO", "downmix encoded audio to mono", setBool(func(c *Config) *bool { return &c.Audio.Mono })},
+	{"transcoder", "POSEIDON_TRANSCODER", "audio transcoder: ffmpeg or fake", setString(func(c *Config) *string { return &c.Audio.Transcoder })},
+	{"ffmpeg", "POSEIDON_FFMPEG", "ffmpeg binary", setString(func(c *Config) *string { return &c.Audio.FFmpeg })},
+	{"ffprobe", "POSEIDON_FFPROBE", "ffprobe binary", setString(func(c *Config) *string { return &c.Audio.FFprobe })},
+	{"high-pass", "POSEIDON_HIGH_PASS", "high-pass filter recordings before encoding", setBool(func(c *Config) *bool { return &c.Preprocess.HighPass })},
+	{"trim-silence", "POSEIDON_TRIM_SILENCE", "trim leading and trailing silence from recordings", setBool(func(c *Config) *bool { return &c.Preprocess.TrimSilence })},
+	{"loudnorm", "POSEIDON_LOUDNORM", "normalize recording loudness to preprocess.target_lufs", setBool(func(c *Config) *bool { return &c.Preprocess.Loudnorm })},
+	{"resample", "POSEIDON_RESAMPLE", "resample recordings to preprocess.sample_rate", setBool(func(c *Config) *bool { return &c.Preprocess.Resample })},
+	{"quality-checks", "POSEIDON_QUALITY_CHECKS", "run audio quality checks on recordings", setBool(func(c *Config) *bool { return &c.Quality.Enabled })},
+	{"test-mode", "POSEIDON_TEST_MODE", "run against an in-process stand-in server", setBool(func(c *Config) *bool { return &c.TestMode })},
+	{"log-level", "POSEIDON_LOG_LEVEL", "log level: debug or info", setString(func(c *Config) *string { return &c.Log.Level })},
+}
+
+// switches are registered as bool flags so they can be given bare, e.g. -mono, or turned off with -mono=false.
+var switches = map[string]bool{
+	"sandbox":        true,
+	"mono":           true,
+	"high-pass":      true,
+	"trim-silence":   true,
+	"loudnorm":       true,
+	"resample":       true,
+	"quality-checks": true,
+	"test-mode":      true,
+}
+
+func RegisterFlags(fs *flag.FlagSet) *string {
+	path := fs.String("config", "", "config file (default "+DefaultPath+")")
+	for _, s := range settings {
+		if switches[s.flag] {
+			fs.Bool(s.flag, false, s.usage+" (env "+s.env+")")
+			continue
+		}
+		fs.String(s.flag, "", s.usage+" (env "+s.env+")")
+	}
+	return path
+}
+
+func (c *Config) ApplyEnv() error {
+	for _, s := range settings {
+		v, ok := os.LookupEnv(s.env)
+		if !ok || v == "" {
+			continue
+		}
+		if err := s.set(c, v); err != nil {
+			return fmt.Errorf("env %s: %w", s.env, err)
+		}
+	}
+	return nil
+}
+
+func (c *Config) ApplyFlags(fs *flag.FlagSet) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		for _, s := range settings {
+			if s.flag != f.Name {
+				continue
+			}
+			if e := s.set(c, f.Value.String()); e != nil {
+				err = fmt.Errorf("flag -%s: %w", f.Name, e)
+			}
+		}
+	})
+	return err
+}
+
+func setString(field func(c *Config) *string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+func setInt(field func(c *Config) *int) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func setBool(field func(c *Config) *bool) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func setDuration(field func(c *Config) *Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = Duration(d)
+		return nil
+	}
+}
@@ -0,0 +1,560 @@
+package fakeserver
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/model"
+)
+
+const (
+	DailyCap        = 10
+	PointsPerUpload = 10
+)
+
+type upload struct {
+	campaignID   string
+	assignmentID string
+	objectKey    string
+	expires      time.Time
+	data         []byte
+	put          bool
+}
+
+type assignment struct {
+	script    model.CampaignScript
+	campaign  string
+	language  string
+	submitted bool
+}
+
+type Server struct {
+	URL string
+
+	ts *httptest.Server
+	mu sync.Mutex
+
+	user        model.UserInfo
+	campaigns   []model.Campaign
+	usedToday   map[string]int
+	assignments map[string]*assignment
+	uploads     map[string]*upload
+	files       []model.FileUploadValidationResponse
+	seq         int
+	inits       int
+
+	failValidations int
+	dropValidations int
+	failPuts        int
+	failGets        int
+	presignTTL      time.Duration
+	assignAhead     int
+}
+
+func New() *Server {
+	s := newServer()
+	s.ts = httptest.NewServer(s.routes())
+	s.URL = s.ts.URL
+	return s
+}
+
+func Listen(addr string) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s: %w", addr, err)
+	}
+	s := newServer()
+	s.ts = httptest.NewUnstartedServer(s.routes())
+	s.ts.Listener.Close()
+	s.ts.Listener = l
+	s.ts.Start()
+	s.URL = s.ts.URL
+	return s, nil
+}
+
+// FailValidations makes the next n POST /files calls answer 503, after the upload was received.
+func (s *Server) FailValidations(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failValidations = n
+}
+
+// DropValidations makes the next n POST /files calls validate the upload and then close the connection
+// without answering, as if the response was lost.
+func (s *Server) DropValidations(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropValidations = n
+}
+
+// FailPuts makes the next n presigned PUTs answer 503 without storing the body.
+func (s *Server) FailPuts(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failPuts = n
+}
+
+// FailGets makes the next n authenticated GETs answer 503 with Retry-After: 1.
+func (s *Server) FailGets(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failGets = n
+}
+
+// AssignAhead makes GET /scripts/next hand out a new assignment on every call, up to n unsubmitted ones per
+// campaign and language, and answer 404 after that. By default the pending assignment is repeated.
+func (s *Server) AssignAhead(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.assignAhead = n
+}
+
+// PresignTTL sets how long new presigned URLs stay valid.
+func (s *Server) PresignTTL(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.presignTTL = d
+}
+
+func (s *Server) Close() {
+	s.ts.Close()
+}
+
+func (s *Server) Endpoints() model.Endpoints {
+	return model.Endpoints{Poseidon: s.URL, Dynamic: s.URL}
+}
+
+func (s *Server) Files() []model.FileUploadValidationResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]model.FileUploadValidationResponse(nil), s.files...)
+}
+
+// InitUploads counts the uploads initialized so far, including re-initializations.
+func (s *Server) InitUploads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inits
+}
+
+func newServer() *Server {
+	return &Server{
+		presignTTL: 15 * time.Minute,
+		user: model.UserInfo{
+			ID:              "fake-user",
+			AuthProvider:    "email",
+			Email:           "fake@example.com",
+			ReferralCode:    "FAKE0001",
+			CurrentRank:     1,
+			PrimaryLanguage: "en",
+			KnownLanguages:  []interface{}{"en"},
+		},
+		campaigns: []model.Campaign{
+			{
+				CampaignName:       "Fake English Reading",
+				VirtualID:          "fake-campaign-en",
+				CampaignType:       "voice",
+				Tags:               []string{"reading"},
+				IsScripted:         true,
+				SupportedLanguages: []string{"en"},
+				RegistrationStatus: "REGISTERED",
+				EndDate:            time.Now().AddDate(0, 1, 0).UTC().Format(time.RFC3339),
+			},
+			{
+				CampaignName:       "Fake Indonesian Reading",
+				VirtualID:          "fake-campaign-id",
+				CampaignType:       "voice",
+				Tags:               []string{"reading"},
+				IsScripted:         true,
+				SupportedLanguages: []string{"id"},
+				RegistrationStatus: "REGISTERED",
+				EndDate:            time.Now().AddDate(0, 1, 0).UTC().Format(time.RFC3339),
+			},
+			{
+				CampaignName:       "Fake Free Speech",
+				VirtualID:          "fake-campaign-free",
+				CampaignType:       "voice",
+				Tags:               []string{"conversation"},
+				IsScripted:         false,
+				SupportedLanguages: []string{"en"},
+				RegistrationStatus: "REGISTERED",
+				EndDate:            time.Now().AddDate(0, 1, 0).UTC().Format(time.RFC3339),
+			},
+			{
+				CampaignName:       "Fake Ended Campaign",
+				VirtualID:          "fake-campaign-ended",
+				CampaignType:       "voice",
+				Tags:               []string{"reading"},
+				IsScripted:         true,
+				SupportedLanguages: []string{"en"},
+				RegistrationStatus: "REGISTERED",
+				EndDate:            time.Now().AddDate(0, 0, -1).UTC().Format(time.RFC3339),
+			},
+		},
+		usedToday:   map[string]int{},
+		assignments: map[string]*assignment{},
+		uploads:     map[string]*upload{},
+	}
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/me", s.authed(s.handleMe))
+	mux.HandleFunc("GET /campaigns", s.authed(s.handleCampaigns))
+	mux.HandleFunc("GET /campaigns/{id}/access", s.authed(s.handleAccess))
+	mux.HandleFunc("GET /scripts/next", s.authed(s.handleNextScript))
+	mux.HandleFunc("POST /files/uploads/{id}", s.authed(s.handleInitUpload))
+	mux.HandleFunc("PUT /presigned/{key...}", s.handlePresignedPut)
+	mux.HandleFunc("POST /files", s.authed(s.handleValidate))
+	mux.HandleFunc("GET /files/{id}", s.authed(s.handleGetFile))
+	return mux
+}
+
+func (s *Server) authed(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") || strings.TrimSpace(strings.TrimPrefix(auth, "Bearer ")) == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"detail": "Not authenticated"})
+			return
+		}
+		if r.Method == http.MethodGet && s.failGet() {
+			w.Header().Set("Retry-After", "1")
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": "Service unavailable"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) failGet() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failGets == 0 {
+		return false
+	}
+	s.failGets--
+	return true
+}
+
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, s.user)
+}
+
+func (s *Server) handleCampaigns(w http.ResponseWriter, r *http.Request) {
+	page := queryInt(r, "page", 1)
+	size := queryInt(r, "size", 50)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := len(s.campaigns)
+	pages := (total + size - 1) / size
+	start := (page - 1) * size
+	end := start + size
+	if start > total {
+		start = total
+	}
+	if end > total {
+		end = total
+	}
+
+	writeJSON(w, http.StatusOK, model.Paginate[model.Campaign]{
+		Items: s.campaigns[start:end],
+		Total: total,
+		Page:  page,
+		Size:  size,
+		Pages: pages,
+	})
+}
+
+func (s *Server) handleAccess(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.campaign(id) == nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"detail": "Campaign not found"})
+		return
+	}
+
+	used := s.usedToday[id]
+	access := model.Access{
+		Allowed:   used < DailyCap,
+		Cap:       DailyCap,
+		UsedToday: used,
+		Remaining: DailyCap - used,
+	}
+	if !access.Allowed {
+		access.Reason = "Daily cap reached"
+	}
+	writeJSON(w, http.StatusOK, access)
+}
+
+func (s *Server) handleNextScript(w http.ResponseWriter, r *http.Request) {
+	campaignID := r.URL.Query().Get("campaign_id")
+	lang := r.URL.Query().Get("language_code")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.campaign(campaignID)
+	if c == nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"detail": "Campaign not found"})
+		return
+	}
+	if !contains(c.SupportedLanguages, lang) {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "Language not supported"})
+		return
+	}
+
+	open := 0
+	for _, a := range s.assignments {
+		if a.campaign == campaignID && a.language == lang && !a.submitted {
+			if s.assignAhead == 0 {
+				writeJSON(w, http.StatusOK, a.script)
+				return
+			}
+			open++
+		}
+	}
+	if s.assignAhead > 0 && open >= s.assignAhead {
+		writeJSON(w, http.StatusNotFound, map[string]any{"detail": "No scripts available"})
+		return
+	}
+
+	s.seq++
+	var sc model.CampaignScript
+	sc.Script.ID = fmt.Sprintf("script-%d", s.seq)
+	sc.Script.IsActive = true
+	sc.Script.LanguageID = "lang-" + lang
+	sc.Script.Language.ID = "lang-" + lang
+	sc.Script.Language.Code = lang
+	sc.Script.Language.Name = strings.ToUpper(lang)
+	sc.Script.Content = fmt.Sprintf("This is fake script number %d for testing.", s.seq)
+	sc.Script.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	sc.Script.UpdatedAt = sc.Script.CreatedAt
+	sc.AssignmentID = fmt.Sprintf("assignment-%d", s.seq)
+	sc.AssignedAt = sc.Script.CreatedAt
+
+	s.assignments[sc.AssignmentID] = &assignment{script: sc, campaign: campaignID, language: lang}
+	writeJSON(w, http.StatusOK, sc)
+}
+
+func (s *Server) handleInitUpload(w http.ResponseWriter, r *http.Request) {
+	campaignID := r.PathValue("id")
+
+	var req model.FileUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]any{"detail": "invalid body"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.assignments[req.ScriptAssignmentID]
+	if !ok || a.campaign != campaignID {
+		writeJSON(w, http.StatusNotFound, map[string]any{"detail": "Assignment not found"})
+		return
+	}
+	if a.submitted {
+		writeJSON(w, http.StatusConflict, map[string]any{"detail": "Assignment already submitted"})
+		return
+	}
+
+	s.inits++
+	fileID := randomID()
+	key := fmt.Sprintf("uploads/%s/%s.webm", campaignID, fileID)
+	signed := time.Now().UTC().Truncate(time.Second)
+	s.uploads[fileID] = &upload{
+		campaignID:   campaignID,
+		assignmentID: req.ScriptAssignmentID,
+		objectKey:    key,
+		expires:      signed.Add(s.presignTTL),
+	}
+
+	writeJSON(w, http.StatusOK, model.FileUploadResponse{
+		PresignedURL: fmt.Sprintf("%s/presigned/%s?X-Amz-Date=%s&X-Amz-Expires=%d",
+			s.URL, key, signed.Format("20060102T150405Z"), int(s.presignTTL.Seconds())),
+		ObjectKey: key,
+		FileID:    fileID,
+	})
+}
+
+func (s *Server) handlePresignedPut(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failPuts > 0 {
+		s.failPuts--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	for _, up := range s.uploads {
+		if up.objectKey == key {
+			if time.Now().After(up.expires) {
+				http.Error(w, "Request has expired", http.StatusForbidden)
+				return
+			}
+			up.data = data
+			up.put = true
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusForbidden)
+}
+
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	var req model.FileUploadValidationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]any{"detail": "invalid body"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failValidations > 0 {
+		s.failValidations--
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{"detail": "Service unavailable"})
+		return
+	}
+
+	up, ok := s.uploads[req.VirtualID]
+	if !ok || up.objectKey != req.ObjectKey {
+		writeJSON(w, http.StatusNotFound, map[string]any{"detail": "Upload not found"})
+		return
+	}
+	if !up.put {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "Object not uploaded"})
+		return
+	}
+
+	sum := sha256.Sum256(up.data)
+	hash := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(hash, req.Sha256Hash) || len(up.data) != req.Filesize {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"detail": "Hash or size mismatch"})
+		return
+	}
+
+	duplicate := false
+	for _, f := range s.files {
+		if f.FileHash == hash {
+			duplicate = true
+			break
+		}
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	res := model.FileUploadValidationResponse{
+		FileName:           req.FileName,
+		FilePath:           up.objectKey,
+		FileType:           req.ContentType,
+		FileSize:           len(up.data),
+		FileHash:           hash,
+		FileURL:            s.URL + "/presigned/" + up.objectKey,
+		FileStatus:         "UPLOADED",
+		VirtualID:          req.VirtualID,
+		CampaignID:         up.campaignID,
+		ID:                 req.VirtualID,
+		UserID:             s.user.ID,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+		IsVerifiedQuality:  !duplicate,
+		IsFlaggedDuplicate: duplicate,
+	}
+	if !duplicate {
+		res.PointsAwarded = PointsPerUpload
+		res.IsRewarded = true
+		s.user.Points += PointsPerUpload
+	}
+
+	s.files = append(s.files, res)
+	s.usedToday[up.campaignID]++
+	if a, ok := s.assignments[up.assignmentID]; ok {
+		a.submitted = true
+	}
+	delete(s.uploads, req.VirtualID)
+
+	if s.dropValidations > 0 {
+		s.dropValidations--
+		if conn, _, err := http.NewResponseController(w).Hijack(); err == nil {
+			conn.Close()
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) handleGetFile(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range s.files {
+		if f.VirtualID == r.PathValue("id") {
+			writeJSON(w, http.StatusOK, f)
+			return
+		}
+	}
+	writeJSON(w, http.StatusNotFound, map[string]any{"detail": "File not found"})
+}
+
+func (s *Server) campaign(id string) *model.Campaign {
+	for i := range s.campaigns {
+		if s.campaigns[i].VirtualID == id {
+			return &s.campaigns[i]
+		}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func queryInt(r *http.Request, key string, def int) int {
+	n, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil || n < 1 {
+		return def
+	}
+	return n
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func randomID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
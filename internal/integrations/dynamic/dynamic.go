@@ -25,14 +25,15 @@ const (
 	dynWalletVer  = "WalletKit/4.29.4"
 )
 
-func RequestEmailVerification(session *model.Session, api *apiclient.ApiClient, log *logger.ClassLogger) (string, error) {
+func RequestEmailVerification(ctx context.Context, session *model.Session, api *apiclient.ApiClient, log *logger.ClassLogger) (string, error) {
 	body := map[string]any{"email": session.Email}
 
 	if log != nil {
 		log.Log("Requesting verification email to Dynamic Auth…", 1500)
 	}
 	res, err := api.Call(
-		fmt.Sprintf("https://app.dynamicauth.com/api/v0/sdk/%s/emailVerifications/create", sdk),
+		ctx,
+		sdkURL(session, "emailVerifications/create"),
 		"POST",
 		body,
 		nil,
@@ -57,19 +58,21 @@ func RequestEmailVerification(session *model.Session, api *apiclient.ApiClient,
 	return uuidStr, nil
 }
 
-func SignIn(session *model.Session, api *apiclient.ApiClient) error {
+func SignIn(ctx context.Context, session *model.Session, api *apiclient.ApiClient) error {
 	log := logger.NewNamed("DynamicAuth", session)
 
-	verificationUUID, err := RequestEmailVerification(session, api, log)
+	verificationUUID, err := RequestEmailVerification(ctx, session, api, log)
 	if err != nil {
 		return err
 	}
 
-	log.Log("Delay and Waiting for verification email in Gmail…", 10000)
+	if err := log.Wait(ctx, "Delay and Waiting for verification email in Gmail…", 10*time.Second); err != nil {
+		return err
+	}
 	code, err := gmail.FetchDynamicAuthCode(
-		context.Background(),
-		"configs/credentials.json",
-		fmt.Sprintf("accounts/%s-data.json", session.Email),
+		ctx,
+		session.CredentialsPath,
+		session.GmailTokenPath,
 		2*time.Minute,
 	)
 	if err != nil {
@@ -105,7 +108,8 @@ func SignIn(session *model.Session, api *apiclient.ApiClient) error {
 	}
 
 	res, err := api.Call(
-		fmt.Sprintf("https://app.dynamicauth.com/api/v0/sdk/%s/emailVerifications/signin", sdk),
+		ctx,
+		sdkURL(session, "emailVerifications/signin"),
 		"POST",
 		body,
 		additionalHeaders,
@@ -142,6 +146,10 @@ func SignIn(session *model.Session, api *apiclient.ApiClient) error {
 	return nil
 }
 
+func sdkURL(session *model.Session, path string) string {
+	return fmt.Sprintf("%s/api/v0/sdk/%s/%s", session.Endpoints.WithDefaults().Dynamic, sdk, path)
+}
+
 func GenerateSessionPublicKey() (string, error) {
 	priv, err := gethcrypto.GenerateKey()
 	if err != nil {
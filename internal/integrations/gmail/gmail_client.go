@@ -39,13 +39,13 @@ func NewService(ctx context.Context, credentialsPath, tokenPath, accountEmail st
 func getClient(ctx context.Context, config *oauth2.Config, tokenPath, accountEmail string) *http.Client {
 	tok, err := tokenFromFile(tokenPath)
 	if err != nil {
-		tok = getTokenFromWeb(config, accountEmail)
+		tok = getTokenFromWeb(ctx, config, accountEmail)
 		saveToken(tokenPath, tok)
 	}
 	return config.Client(ctx, tok)
 }
 
-func getTokenFromWeb(config *oauth2.Config, accountEmail string) *oauth2.Token {
+func getTokenFromWeb(ctx context.Context, config *oauth2.Config, accountEmail string) *oauth2.Token {
 	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
 
 	fmt.Println("Open this link in your browser and paste the full redirect link here:")
@@ -87,7 +87,7 @@ func getTokenFromWeb(config *oauth2.Config, accountEmail string) *oauth2.Token {
 			continue
 		}
 
-		tok, err := config.Exchange(context.Background(), code)
+		tok, err := config.Exchange(ctx, code)
 		if err != nil {
 			fmt.Printf("Token exchange failed: %v\nPlease paste the redirect link/code again.\n", err)
 			continue
@@ -131,6 +131,7 @@ func FetchDynamicAuthCode(ctx context.Context, credentialsPath, tokenPath string
 		list, err := srv.Users.Messages.List("me").
 			Q(q).
 			MaxResults(10).
+			Context(ctx).
 			Do()
 		if err != nil {
 			return "", err
@@ -138,7 +139,7 @@ func FetchDynamicAuthCode(ctx context.Context, credentialsPath, tokenPath string
 		if len(list.Messages) > 0 {
 			var newest *gmail.Message
 			for _, m := range list.Messages {
-				msg, err := srv.Users.Messages.Get("me", m.Id).Format("metadata").MetadataHeaders("Subject").Do()
+				msg, err := srv.Users.Messages.Get("me", m.Id).Format("metadata").MetadataHeaders("Subject").Context(ctx).Do()
 				if err != nil {
 					continue
 				}
@@ -165,6 +166,10 @@ func FetchDynamicAuthCode(ctx context.Context, credentialsPath, tokenPath string
 		if time.Now().After(deadline) {
 			return "", fmt.Errorf("login code email not found within %s", waitUntil)
 		}
-		time.Sleep(5 * time.Second)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
 	}
 }
@@ -0,0 +1,196 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	TypeSubmission = "submission"
+	TypeSnapshot   = "snapshot"
+)
+
+type Entry struct {
+	Type         string `json:"type"`
+	Account      string `json:"account"`
+	CampaignID   string `json:"campaign_id"`
+	CampaignName string `json:"campaign_name,omitempty"`
+	ScriptID     string `json:"script_id,omitempty"`
+	AssignmentID string `json:"assignment_id"`
+	FileID       string `json:"file_id"`
+	ObjectKey    string `json:"object_key"`
+	SHA256       string `json:"sha256"`
+	Size         int    `json:"size"`
+	Language     string `json:"language,omitempty"`
+	Source       string `json:"source,omitempty"`
+
+	FileStatus       string `json:"file_status"`
+	PointsAwarded    int    `json:"points_awarded"`
+	VerifiedQuality  bool   `json:"verified_quality"`
+	Rewarded         bool   `json:"rewarded"`
+	FlaggedDuplicate bool   `json:"flagged_duplicate"`
+	FlaggedBot       bool   `json:"flagged_bot"`
+	FlaggedSpam      bool   `json:"flagged_spam"`
+	// Corrupt marks an upload whose server-side hash or size did not match the local file.
+	Corrupt bool `json:"corrupt,omitempty"`
+
+	// Points and Rank are the account totals seen on a snapshot entry.
+	Points int `json:"points,omitempty"`
+	Rank   int `json:"rank,omitempty"`
+
+	StartedAt   time.Time `json:"started_at"`
+	SubmittedAt time.Time `json:"submitted_at"`
+	RecordedAt  time.Time `json:"recorded_at"`
+}
+
+func (e Entry) Time() time.Time {
+	if e.RecordedAt.IsZero() {
+		return e.SubmittedAt
+	}
+	return e.RecordedAt
+}
+
+type Ledger struct {
+	mu      sync.Mutex
+	path    string
+	f       *os.File
+	entries []Entry
+	// bySHA dedupes audio and leaves out corrupt uploads, so their audio can be sent again; byFileID
+	// holds every submission and tells whether an upload was already recorded.
+	bySHA    map[string]int
+	byFileID map[string]int
+}
+
+func Open(path string) (*Ledger, error) {
+	entries, valid, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("ledger dir: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open ledger: %w", err)
+	}
+	if err := f.Truncate(valid); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("trim ledger: %w", err)
+	}
+	l := &Ledger{path: path, f: f, bySHA: map[string]int{}, byFileID: map[string]int{}}
+	for _, e := range entries {
+		l.add(e)
+	}
+	return l, nil
+}
+
+func Read(path string) ([]Entry, error) {
+	entries, _, err := read(path)
+	return entries, err
+}
+
+func read(path string) ([]Entry, int64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("read ledger: %w", err)
+	}
+
+	// Every append ends with a newline, so a trailing segment without one is a write cut short by a crash.
+	valid := bytes.LastIndexByte(b, '\n') + 1
+
+	var entries []Entry
+	for i, raw := range bytes.Split(b[:valid], []byte("\n")) {
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return nil, 0, fmt.Errorf("ledger %s line %d: %w", path, i+1, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, int64(valid), nil
+}
+
+func (l *Ledger) Append(e Entry) error {
+	if e.Type == "" {
+		e.Type = TypeSubmission
+	}
+	if e.RecordedAt.IsZero() {
+		e.RecordedAt = time.Now()
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("write ledger: %w", err)
+	}
+	if err := l.f.Sync(); err != nil {
+		return fmt.Errorf("sync ledger: %w", err)
+	}
+	l.add(e)
+	return nil
+}
+
+func (l *Ledger) add(e Entry) {
+	l.entries = append(l.entries, e)
+	if e.Type == TypeSubmission && e.FileID != "" {
+		if _, seen := l.byFileID[e.FileID]; !seen {
+			l.byFileID[e.FileID] = len(l.entries) - 1
+		}
+	}
+	if e.Type == TypeSubmission && e.SHA256 != "" && !e.Corrupt {
+		if _, seen := l.bySHA[e.SHA256]; !seen {
+			l.bySHA[e.SHA256] = len(l.entries) - 1
+		}
+	}
+}
+
+// FindSHA256 returns the first submission of the given audio digest, across every account and campaign.
+func (l *Ledger) FindSHA256(hash string) (Entry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i, ok := l.bySHA[hash]
+	if !ok {
+		return Entry{}, false
+	}
+	return l.entries[i], true
+}
+
+// FindFileID returns the submission recorded for an upload, corrupt or not.
+func (l *Ledger) FindFileID(fileID string) (Entry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i, ok := l.byFileID[fileID]
+	if !ok {
+		return Entry{}, false
+	}
+	return l.entries[i], true
+}
+
+func (l *Ledger) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Entry(nil), l.entries...)
+}
+
+func (l *Ledger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
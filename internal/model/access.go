@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type Access struct {
 	Allowed            bool        `json:"allowed"`
 	Reason             string      `json:"reason"`
@@ -9,3 +11,19 @@ type Access struct {
 	TimeoutUntil       interface{} `json:"timeout_until"`
 	IncreasedCapActive bool        `json:"increased_cap_active"`
 }
+
+func (a Access) TimeoutTime() (time.Time, bool) {
+	switch v := a.TimeoutUntil.(type) {
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999", "2006-01-02 15:04:05"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	case float64:
+		if v > 0 {
+			return time.Unix(int64(v), 0), true
+		}
+	}
+	return time.Time{}, false
+}
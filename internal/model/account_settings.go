@@ -0,0 +1,48 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type AccountSettings struct {
+	Email          string            `json:"email"`
+	Label          string            `json:"label,omitempty"`
+	Enabled        *bool             `json:"enabled,omitempty"`
+	Languages      []string          `json:"languages,omitempty"`
+	Campaigns      CampaignSelection `json:"campaigns,omitempty"`
+	RecordingsDir  string            `json:"recordings_dir,omitempty"`
+	GmailTokenPath string            `json:"gmail_token,omitempty"`
+}
+
+type CampaignSelection struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+
+	Types              []string `json:"types,omitempty"`
+	Tags               []string `json:"tags,omitempty"`
+	ExcludeTags        []string `json:"exclude_tags,omitempty"`
+	Languages          []string `json:"languages,omitempty"`
+	RegistrationStatus []string `json:"registration_status,omitempty"`
+	IncludeExpired     bool     `json:"include_expired,omitempty"`
+}
+
+func (a AccountSettings) IsEnabled() bool {
+	return a.Enabled == nil || *a.Enabled
+}
+
+func (a *AccountSettings) UnmarshalJSON(b []byte) error {
+	var email string
+	if err := json.Unmarshal(b, &email); err == nil {
+		*a = AccountSettings{Email: email}
+		return nil
+	}
+
+	type plain AccountSettings
+	var p plain
+	if err := json.Unmarshal(b, &p); err != nil {
+		return fmt.Errorf("account must be an email string or an object: %w", err)
+	}
+	*a = AccountSettings(p)
+	return nil
+}
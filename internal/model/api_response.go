@@ -5,9 +5,13 @@ import "encoding/json"
 type ApiResponse struct {
 	StatusCode int
 	Data       map[string]any
+	Body       []byte
 }
 
 func (r *ApiResponse) Decode(v interface{}) error {
+	if len(r.Body) > 0 && json.Valid(r.Body) {
+		return json.Unmarshal(r.Body, v)
+	}
 	b, err := json.Marshal(r.Data)
 	if err != nil {
 		return err
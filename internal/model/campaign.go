@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type Campaign struct {
 	CampaignName       string   `json:"campaign_name"`
 	IPID               string   `json:"ip_id"`
@@ -21,3 +23,16 @@ type Campaign struct {
 	RegistrationError  *string `json:"registration_error"`
 	CollectionAddress  string  `json:"collection_address"`
 }
+
+// EndTime is when the campaign closes. A date-only end date runs through that whole day.
+func (c Campaign) EndTime() (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999"} {
+		if t, err := time.Parse(layout, c.EndDate); err == nil {
+			return t, true
+		}
+	}
+	if t, err := time.Parse(time.DateOnly, c.EndDate); err == nil {
+		return t.Add(24 * time.Hour), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,28 @@
+package model
+
+import "strings"
+
+const (
+	DefaultPoseidonURL = "https://poseidon-depin-server.storyapis.com"
+	DefaultDynamicURL  = "https://app.dynamicauth.com"
+)
+
+type Endpoints struct {
+	Poseidon string `json:"poseidon"`
+	Dynamic  string `json:"dynamic"`
+	// Sandbox marks a non-production Poseidon server on another machine, e.g. a staging host, as one that
+	// may receive synthetic audio.
+	Sandbox bool `json:"sandbox"`
+}
+
+func (e Endpoints) WithDefaults() Endpoints {
+	if strings.TrimSpace(e.Poseidon) == "" {
+		e.Poseidon = DefaultPoseidonURL
+	}
+	if strings.TrimSpace(e.Dynamic) == "" {
+		e.Dynamic = DefaultDynamicURL
+	}
+	e.Poseidon = strings.TrimRight(e.Poseidon, "/")
+	e.Dynamic = strings.TrimRight(e.Dynamic, "/")
+	return e
+}
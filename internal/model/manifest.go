@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+type ScriptManifest struct {
+	Email     string          `json:"email"`
+	CreatedAt time.Time       `json:"created_at"`
+	Entries   []ManifestEntry `json:"entries"`
+}
+
+type ManifestEntry struct {
+	ScriptID         string `json:"script_id"`
+	AssignmentID     string `json:"assignment_id"`
+	CampaignID       string `json:"campaign_id"`
+	CampaignName     string `json:"campaign_name"`
+	Language         string `json:"language"`
+	Content          string `json:"content"`
+	RomanizedContent string `json:"romanized_content,omitempty"`
+
+	SubmittedAt *time.Time `json:"submitted_at,omitempty"`
+	FileID      string     `json:"file_id,omitempty"`
+}
@@ -1,5 +1,10 @@
 package model
 
+const (
+	SourceTTS       = "tts"
+	SourceRecording = "recording"
+)
+
 type Session struct {
 	JWT    string
 	ID     string
@@ -7,6 +12,19 @@ type Session struct {
 	Email  string
 	Point  int
 
+	Account AccountSettings
+
+	Campaign    string
+	Cap         int
+	UsedToday   int
+	NeedsReview bool
+
+	Endpoints       Endpoints
+	Source          string
+	RecordingsDir   string
+	CredentialsPath string
+	GmailTokenPath  string
+
 	VerificationUUID string
 	LoginCode        string
 }
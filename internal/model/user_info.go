@@ -34,3 +34,31 @@ type UserInfo struct {
 	PrimaryLanguage          interface{}   `json:"primary_language"`
 	KnownLanguages           []interface{} `json:"known_languages"`
 }
+
+func (u UserInfo) SpokenLanguages() []string {
+	var out []string
+	add := func(v interface{}) {
+		var code string
+		switch l := v.(type) {
+		case string:
+			code = l
+		case map[string]interface{}:
+			code, _ = l["code"].(string)
+		}
+		if code == "" {
+			return
+		}
+		for _, have := range out {
+			if have == code {
+				return
+			}
+		}
+		out = append(out, code)
+	}
+
+	add(u.PrimaryLanguage)
+	for _, l := range u.KnownLanguages {
+		add(l)
+	}
+	return out
+}
@@ -0,0 +1,284 @@
+package outbox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/model"
+)
+
+const stateFile = "outbox.json"
+
+type State string
+
+const (
+	StateInitialized State = "initialized"
+	StateUploaded    State = "uploaded"
+	StateValidated   State = "validated"
+)
+
+// Item is one upload in flight. Path points at a copy of the audio spooled inside the outbox directory,
+// so it survives the cleanup of temporary TTS and conversion files.
+type Item struct {
+	FileID       string `json:"file_id"`
+	ObjectKey    string `json:"object_key"`
+	PresignedURL string `json:"presigned_url"`
+	FileName     string `json:"file_name"`
+	SHA256       string `json:"sha256"`
+	Size         int    `json:"size"`
+	Path         string `json:"path"`
+
+	Account      string `json:"account"`
+	CampaignID   string `json:"campaign_id"`
+	CampaignName string `json:"campaign_name"`
+	ScriptID     string `json:"script_id"`
+	AssignmentID string `json:"assignment_id"`
+	Language     string `json:"language"`
+	Source       string `json:"source"`
+
+	State     State                               `json:"state"`
+	Result    *model.FileUploadValidationResponse `json:"result,omitempty"`
+	Attempts  int                                 `json:"attempts"`
+	LastError string                              `json:"last_error,omitempty"`
+	StartedAt time.Time                           `json:"started_at"`
+	UpdatedAt time.Time                           `json:"updated_at"`
+}
+
+type state struct {
+	Pending []Item `json:"pending"`
+	Dead    []Item `json:"dead"`
+}
+
+type Store struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Spooled is audio copied into the outbox under a temporary name, hashed in the same pass.
+type Spooled struct {
+	Path   string
+	SHA256 string
+	Size   int
+}
+
+// Spool copies the audio at src into the outbox, computing its SHA-256 and size on the way. The copy keeps
+// a temporary name until Claim gives it the file ID of its upload; Discard drops it instead.
+func (s *Store) Spool(src string) (Spooled, error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return Spooled{}, err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return Spooled{}, err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(s.dir, "spool-*"+filepath.Ext(src))
+	if err != nil {
+		return Spooled{}, err
+	}
+	fail := func(err error) (Spooled, error) {
+		out.Close()
+		os.Remove(out.Name())
+		return Spooled{}, fmt.Errorf("spool audio: %w", err)
+	}
+
+	h := sha256.New()
+	n, err := io.Copy(out, io.TeeReader(in, h))
+	if err != nil {
+		return fail(err)
+	}
+	if err := out.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return Spooled{}, fmt.Errorf("spool audio: %w", err)
+	}
+	return Spooled{Path: out.Name(), SHA256: hex.EncodeToString(h.Sum(nil)), Size: int(n)}, nil
+}
+
+// Claim renames spooled audio after the file ID of its upload and points sp at the new path.
+func (s *Store) Claim(sp *Spooled, fileID string) error {
+	dst := filepath.Join(s.dir, fileID+filepath.Ext(sp.Path))
+	if err := os.Rename(sp.Path, dst); err != nil {
+		return fmt.Errorf("spool audio: %w", err)
+	}
+	sp.Path = dst
+	return nil
+}
+
+// Discard removes spooled audio that will not be uploaded.
+func (s *Store) Discard(sp Spooled) {
+	_ = os.Remove(sp.Path)
+}
+
+func (s *Store) Put(it Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return err
+	}
+	it.UpdatedAt = time.Now()
+	st.Pending = upsert(st.Pending, it)
+	return s.save(st)
+}
+
+// Replace swaps an item for a re-initialized one that has a new file ID.
+func (s *Store) Replace(oldFileID string, it Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return err
+	}
+	it.UpdatedAt = time.Now()
+	st.Pending, _ = remove(st.Pending, oldFileID)
+	st.Pending = upsert(st.Pending, it)
+	return s.save(st)
+}
+
+// Done drops a validated item and its spooled audio.
+func (s *Store) Done(fileID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return err
+	}
+	var it Item
+	st.Pending, it = remove(st.Pending, fileID)
+	if it.Path != "" {
+		_ = os.Remove(it.Path)
+	}
+	return s.save(st)
+}
+
+// Bury moves an item that keeps failing to the dead-letter list. Its spooled audio is kept for inspection.
+func (s *Store) Bury(it Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return err
+	}
+	it.UpdatedAt = time.Now()
+	st.Pending, _ = remove(st.Pending, it.FileID)
+	st.Dead = upsert(st.Dead, it)
+	return s.save(st)
+}
+
+// Requeue moves a dead item back to pending with its attempts reset.
+func (s *Store) Requeue(fileID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	var it Item
+	st.Dead, it = remove(st.Dead, fileID)
+	if it.FileID == "" {
+		return false, nil
+	}
+	it.Attempts = 0
+	it.UpdatedAt = time.Now()
+	st.Pending = upsert(st.Pending, it)
+	return true, s.save(st)
+}
+
+func (s *Store) Pending(account string) ([]Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var out []Item
+	for _, it := range st.Pending {
+		if account == "" || it.Account == account {
+			out = append(out, it)
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) Dead() ([]Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return st.Dead, nil
+}
+
+func upsert(items []Item, it Item) []Item {
+	for i := range items {
+		if items[i].FileID == it.FileID {
+			items[i] = it
+			return items
+		}
+	}
+	return append(items, it)
+}
+
+func remove(items []Item, fileID string) ([]Item, Item) {
+	for i := range items {
+		if items[i].FileID == fileID {
+			it := items[i]
+			return append(items[:i], items[i+1:]...), it
+		}
+	}
+	return items, Item{}
+}
+
+func (s *Store) load() (state, error) {
+	var st state
+	b, err := os.ReadFile(filepath.Join(s.dir, stateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return st, nil
+		}
+		return st, fmt.Errorf("read outbox: %w", err)
+	}
+	if err := json.Unmarshal(b, &st); err != nil {
+		return st, fmt.Errorf("decode outbox: %w", err)
+	}
+	return st, nil
+}
+
+func (s *Store) save(st state) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, stateFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,101 @@
+package recording
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const queueFile = "to_record.json"
+
+type QueueItem struct {
+	ScriptID         string    `json:"script_id"`
+	AssignmentID     string    `json:"assignment_id"`
+	CampaignID       string    `json:"campaign_id"`
+	CampaignName     string    `json:"campaign_name"`
+	Language         string    `json:"language"`
+	Content          string    `json:"content"`
+	RomanizedContent string    `json:"romanized_content,omitempty"`
+	Note             string    `json:"note,omitempty"`
+	QueuedAt         time.Time `json:"queued_at"`
+}
+
+var queueMu sync.Mutex
+
+func QueuePath(dir string) string {
+	return filepath.Join(dir, queueFile)
+}
+
+func LoadQueue(dir string) ([]QueueItem, error) {
+	b, err := os.ReadFile(QueuePath(dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read record queue: %w", err)
+	}
+	var items []QueueItem
+	if err := json.Unmarshal(b, &items); err != nil {
+		return nil, fmt.Errorf("decode record queue: %w", err)
+	}
+	return items, nil
+}
+
+func Enqueue(dir string, item QueueItem) (bool, error) {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+
+	items, err := LoadQueue(dir)
+	if err != nil {
+		return false, err
+	}
+	for i, it := range items {
+		if it.ScriptID == item.ScriptID && it.AssignmentID == item.AssignmentID {
+			if it.Note == item.Note {
+				return false, nil
+			}
+			items[i].Note = item.Note
+			return false, saveQueue(dir, items)
+		}
+	}
+	if item.QueuedAt.IsZero() {
+		item.QueuedAt = time.Now()
+	}
+	items = append(items, item)
+	return true, saveQueue(dir, items)
+}
+
+func Dequeue(dir string, scriptID, assignmentID string) error {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+
+	items, err := LoadQueue(dir)
+	if err != nil {
+		return err
+	}
+	kept := items[:0]
+	for _, it := range items {
+		if it.ScriptID == scriptID || (assignmentID != "" && it.AssignmentID == assignmentID) {
+			continue
+		}
+		kept = append(kept, it)
+	}
+	if len(kept) == len(items) {
+		return nil
+	}
+	return saveQueue(dir, kept)
+}
+
+func saveQueue(dir string, items []QueueItem) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(QueuePath(dir), b, 0o644)
+}
@@ -0,0 +1,101 @@
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var ErrNotFound = errors.New("recording not found")
+
+var audioExts = map[string]bool{
+	".webm": true,
+	".ogg":  true,
+	".opus": true,
+	".wav":  true,
+	".flac": true,
+	".m4a":  true,
+	".mp3":  true,
+}
+
+func IsAudioFile(name string) bool {
+	return audioExts[strings.ToLower(filepath.Ext(name))]
+}
+
+func Find(dir string, keys ...string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("read recordings dir: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !IsAudioFile(e.Name()) {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+
+	for _, key := range keys {
+		if strings.TrimSpace(key) == "" {
+			continue
+		}
+		for _, name := range names {
+			stem := strings.TrimSuffix(name, filepath.Ext(name))
+			if strings.EqualFold(stem, key) {
+				return filepath.Join(dir, name), nil
+			}
+		}
+	}
+	return "", ErrNotFound
+}
+
+func FindForLanguage(dir, lang string, keys ...string) (string, error) {
+	if lang != "" {
+		if p, err := Find(filepath.Join(dir, lang), keys...); err == nil || !errors.Is(err, ErrNotFound) {
+			return p, err
+		}
+	}
+	return Find(dir, keys...)
+}
+
+func MarkSubmitted(path string) error {
+	return moveInto(path, "submitted")
+}
+
+func MarkRejected(path string) error {
+	return moveInto(path, "rejected")
+}
+
+func MarkDuplicate(path string) error {
+	return moveInto(path, "duplicate")
+}
+
+// ProcessedPath is where the cleaned-up copy of a recording is kept, next to the original.
+// Its stem never equals a script ID, so Find does not pick it up as a recording.
+func ProcessedPath(path string) string {
+	stem := strings.TrimSuffix(path, filepath.Ext(path))
+	return stem + ".processed.wav"
+}
+
+func moveInto(path, sub string) error {
+	dir := filepath.Join(filepath.Dir(path), sub)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if err := os.Rename(path, filepath.Join(dir, filepath.Base(path))); err != nil {
+		return err
+	}
+	processed := ProcessedPath(path)
+	if err := os.Rename(processed, filepath.Join(dir, filepath.Base(processed))); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
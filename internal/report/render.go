@@ -0,0 +1,131 @@
+package report
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	FormatTable = "table"
+	FormatCSV   = "csv"
+	FormatJSON  = "json"
+	FormatHTML  = "html"
+)
+
+func Write(w io.Writer, r Report, format string) error {
+	switch format {
+	case FormatTable, "":
+		return writeTable(w, r)
+	case FormatCSV:
+		return writeCSV(w, r)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case FormatHTML:
+		return htmlReport.Execute(w, r)
+	default:
+		return fmt.Errorf("unknown report format %q (use table, csv, json or html)", format)
+	}
+}
+
+func writeTable(w io.Writer, r Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Period\t%s\n\n", period(r))
+	for _, a := range r.Accounts {
+		fmt.Fprintf(tw, "%s\n", a.Account)
+		if p := a.Latest(); p != nil {
+			fmt.Fprintf(tw, "  Points\t%d (%+d)\n", p.Points, a.PointsDelta())
+			fmt.Fprintf(tw, "  Rank\t%d (%+d)\n", p.Rank, a.RankDelta())
+		}
+		fmt.Fprintln(tw, "  CAMPAIGN\tUPLOADS\tPOINTS\tVERIFIED\tDUPLICATE\tSPAM\tBOT\tCORRUPT")
+		for _, c := range a.Campaigns {
+			tableRow(tw, "  "+campaignLabel(c), c.Stats)
+		}
+		tableRow(tw, "  TOTAL", a.Totals)
+		fmt.Fprintln(tw)
+	}
+	tableRow(tw, "ALL ACCOUNTS", r.Totals)
+	return tw.Flush()
+}
+
+func tableRow(w io.Writer, label string, s Stats) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%d\t%d\t%d\t%d\n",
+		label, s.Uploads, s.PointsAwarded, percent(s.VerifiedRate), s.FlaggedDuplicate, s.FlaggedSpam, s.FlaggedBot, s.Corrupt)
+}
+
+func writeCSV(w io.Writer, r Report) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{
+		"account", "campaign_id", "campaign_name", "uploads", "points_awarded", "verified", "verified_rate",
+		"flagged_duplicate", "flagged_spam", "flagged_bot", "corrupt", "points", "points_delta", "rank", "rank_delta",
+	})
+	row := func(account, id, name string, s Stats, tail ...string) {
+		rec := []string{
+			account, id, name,
+			strconv.Itoa(s.Uploads), strconv.Itoa(s.PointsAwarded), strconv.Itoa(s.Verified),
+			strconv.FormatFloat(s.VerifiedRate, 'f', 4, 64),
+			strconv.Itoa(s.FlaggedDuplicate), strconv.Itoa(s.FlaggedSpam), strconv.Itoa(s.FlaggedBot),
+			strconv.Itoa(s.Corrupt),
+		}
+		rec = append(rec, tail...)
+		for len(rec) < 15 {
+			rec = append(rec, "")
+		}
+		_ = cw.Write(rec)
+	}
+	for _, a := range r.Accounts {
+		for _, c := range a.Campaigns {
+			row(a.Account, c.CampaignID, c.CampaignName, c.Stats)
+		}
+		var tail []string
+		if p := a.Latest(); p != nil {
+			tail = []string{
+				strconv.Itoa(p.Points), strconv.Itoa(a.PointsDelta()),
+				strconv.Itoa(p.Rank), strconv.Itoa(a.RankDelta()),
+			}
+		}
+		row(a.Account, "", "TOTAL", a.Totals, tail...)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func campaignLabel(c Campaign) string {
+	if c.CampaignName == "" {
+		return c.CampaignID
+	}
+	return c.CampaignName
+}
+
+func percent(f float64) string {
+	return strconv.FormatFloat(f*100, 'f', 1, 64) + "%"
+}
+
+func period(r Report) string {
+	from, to := "start", "now"
+	if !r.From.IsZero() {
+		from = r.From.Format(time.DateOnly)
+	}
+	if !r.To.IsZero() {
+		to = r.To.Add(-time.Nanosecond).Format(time.DateOnly)
+	}
+	return from + " .. " + to
+}
+
+//go:embed report.html.tmpl
+var htmlTemplate string
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent":  percent,
+	"period":   period,
+	"campaign": campaignLabel,
+	"date":     func(t time.Time) string { return t.Format(time.DateTime) },
+}).Parse(htmlTemplate))
@@ -0,0 +1,164 @@
+package report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/ledger"
+)
+
+type Options struct {
+	From    time.Time
+	To      time.Time
+	Account string
+}
+
+type Report struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	From        time.Time `json:"from,omitzero"`
+	To          time.Time `json:"to,omitzero"`
+	Totals      Stats     `json:"totals"`
+	Accounts    []Account `json:"accounts"`
+}
+
+type Account struct {
+	Account   string     `json:"account"`
+	Totals    Stats      `json:"totals"`
+	Campaigns []Campaign `json:"campaigns"`
+	Trend     []Point    `json:"trend"`
+}
+
+type Campaign struct {
+	CampaignID   string `json:"campaign_id"`
+	CampaignName string `json:"campaign_name"`
+	Stats
+}
+
+type Stats struct {
+	Uploads          int     `json:"uploads"`
+	PointsAwarded    int     `json:"points_awarded"`
+	Verified         int     `json:"verified"`
+	VerifiedRate     float64 `json:"verified_rate"`
+	FlaggedDuplicate int     `json:"flagged_duplicate"`
+	FlaggedSpam      int     `json:"flagged_spam"`
+	FlaggedBot       int     `json:"flagged_bot"`
+	Corrupt          int     `json:"corrupt"`
+}
+
+type Point struct {
+	At     time.Time `json:"at"`
+	Points int       `json:"points"`
+	Rank   int       `json:"rank"`
+}
+
+// PointsDelta and RankDelta compare the first and last snapshot in range; a negative rank delta is a climb.
+func (a Account) PointsDelta() int {
+	if len(a.Trend) == 0 {
+		return 0
+	}
+	return a.Trend[len(a.Trend)-1].Points - a.Trend[0].Points
+}
+
+func (a Account) RankDelta() int {
+	if len(a.Trend) == 0 {
+		return 0
+	}
+	return a.Trend[len(a.Trend)-1].Rank - a.Trend[0].Rank
+}
+
+func (a Account) Latest() *Point {
+	if len(a.Trend) == 0 {
+		return nil
+	}
+	return &a.Trend[len(a.Trend)-1]
+}
+
+func (s *Stats) add(e ledger.Entry) {
+	s.Uploads++
+	s.PointsAwarded += e.PointsAwarded
+	if e.VerifiedQuality {
+		s.Verified++
+	}
+	if e.FlaggedDuplicate {
+		s.FlaggedDuplicate++
+	}
+	if e.FlaggedSpam {
+		s.FlaggedSpam++
+	}
+	if e.FlaggedBot {
+		s.FlaggedBot++
+	}
+	if e.Corrupt {
+		s.Corrupt++
+	}
+	s.VerifiedRate = float64(s.Verified) / float64(s.Uploads)
+}
+
+func (o Options) includes(e ledger.Entry) bool {
+	if o.Account != "" && e.Account != o.Account {
+		return false
+	}
+	t := e.Time()
+	if !o.From.IsZero() && t.Before(o.From) {
+		return false
+	}
+	if !o.To.IsZero() && !t.Before(o.To) {
+		return false
+	}
+	return true
+}
+
+func Build(entries []ledger.Entry, opts Options) Report {
+	r := Report{GeneratedAt: time.Now(), From: opts.From, To: opts.To}
+
+	accounts := map[string]*Account{}
+	campaigns := map[string]map[string]*Campaign{}
+	account := func(email string) *Account {
+		a, ok := accounts[email]
+		if !ok {
+			a = &Account{Account: email}
+			accounts[email] = a
+			campaigns[email] = map[string]*Campaign{}
+		}
+		return a
+	}
+
+	for _, e := range entries {
+		if !opts.includes(e) {
+			continue
+		}
+		a := account(e.Account)
+		switch e.Type {
+		case ledger.TypeSnapshot:
+			// Snapshots are taken every cycle; keep only the ones where something moved.
+			if p := a.Latest(); p != nil && p.Points == e.Points && p.Rank == e.Rank {
+				continue
+			}
+			a.Trend = append(a.Trend, Point{At: e.Time(), Points: e.Points, Rank: e.Rank})
+		case ledger.TypeSubmission:
+			c, ok := campaigns[e.Account][e.CampaignID]
+			if !ok {
+				c = &Campaign{CampaignID: e.CampaignID}
+				campaigns[e.Account][e.CampaignID] = c
+			}
+			if e.CampaignName != "" {
+				c.CampaignName = e.CampaignName
+			}
+			c.add(e)
+			a.Totals.add(e)
+			r.Totals.add(e)
+		}
+	}
+
+	for email, a := range accounts {
+		for _, c := range campaigns[email] {
+			a.Campaigns = append(a.Campaigns, *c)
+		}
+		sort.Slice(a.Campaigns, func(i, j int) bool {
+			return a.Campaigns[i].CampaignName+a.Campaigns[i].CampaignID < a.Campaigns[j].CampaignName+a.Campaigns[j].CampaignID
+		})
+		r.Accounts = append(r.Accounts, *a)
+	}
+	sort.Slice(r.Accounts, func(i, j int) bool { return r.Accounts[i].Account < r.Accounts[j].Account })
+	return r
+}
@@ -0,0 +1,143 @@
+package review
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Pause struct {
+	Account      string    `json:"account"`
+	CampaignID   string    `json:"campaign_id"`
+	CampaignName string    `json:"campaign_name"`
+	FileID       string    `json:"file_id"`
+	Flags        []string  `json:"flags"`
+	PausedAt     time.Time `json:"paused_at"`
+}
+
+func (p Pause) Reason() string {
+	r := "flagged " + strings.Join(p.Flags, ", ")
+	if p.FileID != "" {
+		r += " on file " + p.FileID
+	}
+	return r
+}
+
+// Store is re-read on every call so a resume from another process is picked up by running workers.
+type Store struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+func (s *Store) List() ([]Pause, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+func (s *Store) Pause(p Pause) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pauses, err := s.load()
+	if err != nil {
+		return err
+	}
+	if p.PausedAt.IsZero() {
+		p.PausedAt = time.Now()
+	}
+	for i, have := range pauses {
+		if have.Account == p.Account && have.CampaignID == p.CampaignID {
+			pauses[i] = p
+			return s.save(pauses)
+		}
+	}
+	return s.save(append(pauses, p))
+}
+
+func (s *Store) Paused(account, campaignID string) (Pause, bool, error) {
+	pauses, err := s.List()
+	if err != nil {
+		return Pause{}, false, err
+	}
+	for _, p := range pauses {
+		if p.Account == account && p.CampaignID == campaignID {
+			return p, true, nil
+		}
+	}
+	return Pause{}, false, nil
+}
+
+func (s *Store) NeedsReview(account string) (bool, error) {
+	pauses, err := s.List()
+	if err != nil {
+		return false, err
+	}
+	for _, p := range pauses {
+		if p.Account == account {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Resume lifts the pauses of an account, or only the one for campaignID when it is set.
+func (s *Store) Resume(account, campaignID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pauses, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+	kept := pauses[:0]
+	for _, p := range pauses {
+		if p.Account == account && (campaignID == "" || p.CampaignID == campaignID) {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	removed := len(pauses) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, s.save(kept)
+}
+
+func (s *Store) load() ([]Pause, error) {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read review state: %w", err)
+	}
+	var pauses []Pause
+	if err := json.Unmarshal(b, &pauses); err != nil {
+		return nil, fmt.Errorf("decode review state: %w", err)
+	}
+	return pauses, nil
+}
+
+func (s *Store) save(pauses []Pause) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(pauses, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -4,18 +4,34 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+
+	"github.com/widiskel/poseidon-voice-bot/internal/model"
 )
 
-func LoadAccounts(path string) ([]string, error) {
+func LoadAccounts(path string) ([]model.AccountSettings, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("open accounts file: %w", err)
 	}
 	defer f.Close()
 
-	var accs []string
+	var accs []model.AccountSettings
 	if err := json.NewDecoder(f).Decode(&accs); err != nil {
 		return nil, fmt.Errorf("decode accounts: %w", err)
 	}
+
+	seen := make(map[string]bool, len(accs))
+	for i := range accs {
+		accs[i].Email = strings.TrimSpace(accs[i].Email)
+		if accs[i].Email == "" {
+			return nil, fmt.Errorf("accounts[%d]: email is required", i)
+		}
+		key := strings.ToLower(accs[i].Email)
+		if seen[key] {
+			return nil, fmt.Errorf("accounts[%d]: duplicate email %s", i, accs[i].Email)
+		}
+		seen[key] = true
+	}
 	return accs, nil
 }
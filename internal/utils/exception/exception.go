@@ -1,68 +1,143 @@
 package exception
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
 
 	"github.com/widiskel/poseidon-voice-bot/internal/client/apiclient"
-	"github.com/widiskel/poseidon-voice-bot/internal/utils/logger"
+	"github.com/widiskel/poseidon-voice-bot/internal/client/breaker"
 )
 
-func HandleError(log *logger.ClassLogger, err error) (shouldStop bool) {
-	if apiErr, ok := err.(*apiclient.Error); ok {
-		return handleAPIError(log, apiErr)
+type Class string
+
+const (
+	ClassAuthExpired    Class = "auth_expired"
+	ClassRateLimited    Class = "rate_limited"
+	ClassQuotaExhausted Class = "quota_exhausted"
+	ClassNotFound       Class = "not_found"
+	ClassServer         Class = "server"
+	ClassTransport      Class = "transport"
+	ClassCorruptUpload  Class = "corrupt_upload"
+	ClassClientError    Class = "client_error"
+	ClassFatal          Class = "fatal"
+)
+
+var (
+	ErrAuthExpired    = errors.New("auth expired")
+	ErrRateLimited    = errors.New("rate limited")
+	ErrQuotaExhausted = errors.New("quota exhausted")
+	ErrNotFound       = errors.New("not found")
+	ErrServer         = errors.New("server error")
+	ErrTransport      = errors.New("transport error")
+	ErrCorruptUpload  = errors.New("corrupt upload")
+	ErrClientError    = errors.New("client error")
+	ErrFatal          = errors.New("fatal error")
+)
+
+var sentinels = map[Class]error{
+	ClassAuthExpired:    ErrAuthExpired,
+	ClassRateLimited:    ErrRateLimited,
+	ClassQuotaExhausted: ErrQuotaExhausted,
+	ClassNotFound:       ErrNotFound,
+	ClassServer:         ErrServer,
+	ClassTransport:      ErrTransport,
+	ClassCorruptUpload:  ErrCorruptUpload,
+	ClassClientError:    ErrClientError,
+	ClassFatal:          ErrFatal,
+}
+
+// Error is an error tagged with its class. errors.Is matches the class sentinel, e.g. ErrRateLimited,
+// and everything the wrapped error matches.
+type Error struct {
+	Class      Class
+	Message    string
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func (e *Error) Is(target error) bool { return sentinels[e.Class] == target }
+
+// Wrap tags err with a class, for failures that are not API responses.
+func Wrap(class Class, err error) error {
+	if err == nil {
+		return nil
 	}
-	return handleTransportError(log, err)
+	return &Error{Class: class, Message: err.Error(), Err: err}
 }
 
-func handleAPIError(log *logger.ClassLogger, apiErr *apiclient.Error) (shouldStop bool) {
-	msg := extractErrMessage(apiErr.Body)
+var (
+	reQuota      = regexp.MustCompile(`(?i)quota|daily (limit|cap)|limit reached|cap reached`)
+	reCloudflare = regexp.MustCompile(`(?i)cloudflare|cf-ray|attention required`)
+)
 
+// ForStatus classifies an HTTP status and its response body. Only a Cloudflare block is fatal; any other
+// request the API rejects is a client error.
+func ForStatus(code int, body string) Class {
 	switch {
-	case apiErr.IsStatus(401):
-		log.JustLog(fmt.Sprintf("401 Unauthorized: %s", msg))
-		log.Log("JWT invalid/expired.", 3000)
-		return false
-
-	case apiErr.IsStatus(502):
-		log.JustLog(fmt.Sprintf("502 Cloudflare Block: %s", msg))
-		log.Log("Blocked by cloudflare, please open page on browser for unblock.", 3000)
-		return true
-
-	case apiErr.IsStatus(403):
-		log.JustLog(fmt.Sprintf("403 Forbidden: %s", msg))
-		log.Log("Forbidden. Retrying after 30 seconds…", 30000)
-		return false
-
-	case apiErr.IsStatus(404):
-		log.JustLog(fmt.Sprintf("404 Not Found: %s", msg))
-		log.Log("Resource not found. Retrying after 30 seconds…", 30000)
-		return false
-
-	case apiErr.IsStatus(429):
-		log.JustLog(fmt.Sprintf("429 Too Many Requests: %s", msg))
-		log.Log("Rate limited. Backing off 60 seconds…", 60000)
-		return false
-
-	case apiErr.IsServerError():
-		log.JustLog(fmt.Sprintf("%d Server Error: %s", apiErr.StatusCode, msg))
-		log.Log("Server error. Retrying after 15 seconds…", 15000)
-		return false
-
-	default:
-		log.JustLog(fmt.Sprintf("%d Client Error: %s", apiErr.StatusCode, msg))
-		log.Log("Client error. Retrying after 30 seconds…", 30000)
-		return false
+	case (code == http.StatusForbidden || code == http.StatusBadGateway) && reCloudflare.MatchString(body):
+		return ClassFatal
+	case code == http.StatusUnauthorized:
+		return ClassAuthExpired
+	case (code == http.StatusTooManyRequests || code == http.StatusForbidden) && reQuota.MatchString(body):
+		return ClassQuotaExhausted
+	case code == http.StatusTooManyRequests:
+		return ClassRateLimited
+	case code == http.StatusNotFound:
+		return ClassNotFound
+	case code >= 500:
+		return ClassServer
 	}
+	return ClassClientError
 }
 
-func handleTransportError(log *logger.ClassLogger, err error) (shouldStop bool) {
-	log.JustLog(fmt.Sprintf("HTTP transport error: %v", err))
-	log.Log("Network error. Retrying after 10 seconds…", 10000)
-	return false
+// Classify returns the class of err. A class set with Wrap wins; API errors are classified by status
+// and body; network failures are transport errors; anything else is fatal.
+func Classify(err error) *Error {
+	var ce *Error
+	if errors.As(err, &ce) {
+		return ce
+	}
+
+	var openErr *breaker.OpenError
+	if errors.As(err, &openErr) {
+		return &Error{Class: ClassServer, Message: err.Error(), RetryAfter: max(time.Until(openErr.Until), 0), Err: err}
+	}
+
+	var apiErr *apiclient.Error
+	if errors.As(err, &apiErr) {
+		ce := &Error{
+			Class:   ForStatus(apiErr.StatusCode, apiErr.Body),
+			Message: fmt.Sprintf("%d %s", apiErr.StatusCode, BodyMessage(apiErr.Body)),
+			Err:     err,
+		}
+		if d, ok := apiclient.RetryAfter(apiErr.Header); ok {
+			ce.RetryAfter = d
+		}
+		if ce.Class == ClassFatal && reCloudflare.MatchString(apiErr.Body) {
+			ce.Message = fmt.Sprintf("%d blocked by Cloudflare, open the site in a browser to unblock", apiErr.StatusCode)
+		}
+		return ce
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return &Error{Class: ClassTransport, Message: err.Error(), Err: err}
+	}
+	return &Error{Class: ClassFatal, Message: err.Error(), Err: err}
 }
 
-func extractErrMessage(body string) string {
+// BodyMessage picks the "message" or "detail" field out of a JSON error body, or returns the body as is.
+func BodyMessage(body string) string {
 	var m map[string]any
 	if err := json.Unmarshal([]byte(body), &m); err == nil {
 		if v, ok := m["message"].(string); ok && v != "" {
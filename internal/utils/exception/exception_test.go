@@ -0,0 +1,32 @@
+package exception
+
+import "testing"
+
+func TestForStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		code int
+		body string
+		want Class
+	}{
+		{"unauthorized", 401, `{"message":"jwt expired"}`, ClassAuthExpired},
+		{"rate limited", 429, `{"message":"slow down"}`, ClassRateLimited},
+		{"quota on 429", 429, `{"message":"Daily limit reached"}`, ClassQuotaExhausted},
+		{"quota on 403", 403, `{"message":"quota exhausted for today"}`, ClassQuotaExhausted},
+		{"plain 403", 403, `{"message":"forbidden"}`, ClassClientError},
+		{"bad request", 400, `{"message":"unsupported language"}`, ClassClientError},
+		{"conflict", 409, `{"detail":"already submitted"}`, ClassClientError},
+		{"not found", 404, `{"message":"no script"}`, ClassNotFound},
+		{"server", 503, `{"message":"unavailable"}`, ClassServer},
+		{"bad gateway", 502, "bad gateway", ClassServer},
+		{"cloudflare 403", 403, "<title>Attention Required! | Cloudflare</title>", ClassFatal},
+		{"cloudflare 502", 502, "cloudflare: bad gateway", ClassFatal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ForStatus(tt.code, tt.body); got != tt.want {
+				t.Fatalf("ForStatus(%d) = %s, want %s", tt.code, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -8,6 +9,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/widiskel/poseidon-voice-bot/internal/model"
@@ -19,8 +21,21 @@ var (
 	fileLogger *log.Logger
 	once       sync.Once
 	logFile    *os.File
+	debug      = true
+	paced      atomic.Bool
 )
 
+func init() { paced.Store(true) }
+
+func SetLevel(level string) {
+	debug = strings.EqualFold(level, "debug")
+}
+
+// SetPacing turns off the pauses Log holds each status on screen, e.g. for tests that drive a worker.
+func SetPacing(on bool) {
+	paced.Store(on)
+}
+
 func Init(path string) error {
 	var err error
 	once.Do(func() {
@@ -80,7 +95,7 @@ func (l *ClassLogger) Log(msg string, durationMs ...int) {
 		fileLogger.Printf("[%s][%s] %s", l.class, funcName, msg)
 	}
 
-	if totalDuration > 0 {
+	if totalDuration > 0 && paced.Load() {
 		interval := 1 * time.Second
 
 		for remaining := totalDuration; remaining > 0; remaining -= interval {
@@ -97,6 +112,36 @@ func (l *ClassLogger) Log(msg string, durationMs ...int) {
 	spinner.UpdateStatus(*l.session, msg, 0)
 }
 
+func (l *ClassLogger) Wait(ctx context.Context, msg string, d time.Duration) error {
+	if fileLogger != nil {
+		funcName := callerFunc(2)
+		fileLogger.Printf("[%s][%s] %s", l.class, funcName, msg)
+	}
+
+	deadline := time.Now().Add(d)
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		spinner.UpdateStatus(*l.session, msg, time.Until(deadline))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			spinner.UpdateStatus(*l.session, msg, 0)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Progress only refreshes the status line, for updates too frequent for the log file.
+func (l *ClassLogger) Progress(msg string) {
+	spinner.UpdateStatus(*l.session, msg, 0)
+}
+
 func (l *ClassLogger) JustLog(msg string) {
 
 	if fileLogger != nil {
@@ -105,6 +150,13 @@ func (l *ClassLogger) JustLog(msg string) {
 	}
 }
 
+func (l *ClassLogger) Debug(msg string) {
+	if fileLogger != nil && debug {
+		funcName := callerFunc(2)
+		fileLogger.Printf("[%s][%s] %s", l.class, funcName, msg)
+	}
+}
+
 func (l *ClassLogger) LogObject(msg string, obj interface{}) {
 	if fileLogger != nil {
 		formattedString, err := utils.FormatObject(obj)
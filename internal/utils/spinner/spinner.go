@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/pterm/pterm"
+	"github.com/widiskel/poseidon-voice-bot/internal/client/breaker"
 	"github.com/widiskel/poseidon-voice-bot/internal/model"
 )
 
@@ -21,28 +22,54 @@ func StartUISystem() {
 }
 
 func StopUISystem() {
-	if multi != nil {
+	mu.Lock()
+	defer mu.Unlock()
+	if multi != nil && multi.IsActive {
 		multi.Stop()
 	}
 }
 
+// UpdateStatus redraws the account's panel. It does nothing until StartUISystem has run, so workers can
+// also run headless, e.g. in tests.
 func UpdateStatus(session model.Session, status string, remainingDelay time.Duration) {
 	mu.Lock()
 	defer mu.Unlock()
+	if multi == nil {
+		return
+	}
 
 	delayStr := FormatDelay(remainingDelay)
 
+	title := fmt.Sprintf("Account %d", session.AccIdx+1)
+	if session.Account.Label != "" {
+		title += " - " + session.Account.Label
+	}
+	if session.NeedsReview {
+		title += " [NEEDS REVIEW]"
+	}
+
+	campaign := "-"
+	if session.Campaign != "" {
+		campaign = session.Campaign
+		if session.Cap > 0 {
+			campaign += fmt.Sprintf(" (%d/%d today)", session.UsedToday, session.Cap)
+		}
+	}
+
 	content := fmt.Sprintf(`
-=============== Account %d ================
+=============== %s ================
 Email    : %s
 Points   : %d
+Campaign : %s%s
 
 Status   : %s
 Delay    : %s
 ========================================`,
-		session.AccIdx+1,
+		title,
 		session.Email,
 		session.Point,
+		campaign,
+		circuits(),
 		status,
 		delayStr)
 
@@ -57,6 +84,19 @@ Delay    : %s
 	}
 }
 
+// circuits lists the API hosts whose breaker is open or probing; the breakers are shared, so every panel shows them.
+func circuits() string {
+	var out string
+	for _, st := range breaker.Tripped() {
+		line := fmt.Sprintf("%s %s", st.Host, st.State)
+		if st.State == breaker.Open {
+			line += ", probing in " + FormatDelay(max(time.Until(st.Until), 0))
+		}
+		out += "\nCircuit  : " + line
+	}
+	return out
+}
+
 func SetSpinnerSuccess(session model.Session, finalMessage string) {
 	mu.Lock()
 	defer mu.Unlock()
@@ -16,9 +16,17 @@ type SavedToken struct {
 	Raw       map[string]any `json:"raw,omitempty"`
 }
 
+var tokenDir = "accounts"
+
+func SetTokenDir(dir string) {
+	if dir != "" {
+		tokenDir = dir
+	}
+}
+
 func filePathFor(email string) string {
 	safe := strings.ReplaceAll(email, string(os.PathSeparator), "_")
-	return filepath.Join("accounts", safe+"-token.json")
+	return filepath.Join(tokenDir, safe+"-token.json")
 }
 
 func SaveToken(email string, raw map[string]any) error {
@@ -33,7 +41,7 @@ func SaveToken(email string, raw map[string]any) error {
 		ExpiresAt: int64(exp),
 		Raw:       raw,
 	}
-	if err := os.MkdirAll("accounts", 0o755); err != nil {
+	if err := os.MkdirAll(tokenDir, 0o755); err != nil {
 		return err
 	}
 	b, err := json.MarshalIndent(data, "", "  ")